@@ -0,0 +1,105 @@
+package cbor
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+)
+
+var bigIntType = reflect.TypeOf(big.Int{})
+
+var bigOne = big.NewInt(1)
+
+// writeBigInt writes n as a regular integer if it fits in 64 bits, or as a
+// bignum with the tag 2 or 3 otherwise
+func (e *Encoder) writeBigInt(n *big.Int) error {
+	var (
+		major byte = majorPositiveInteger
+		tag        = uint64(tagPositiveBignum)
+		value      = n
+	)
+	if n.Sign() < 0 {
+		// negative integers are encoded as -1 - n
+		major = majorNegativeInteger
+		tag = tagNegativeBignum
+		value = new(big.Int).Neg(n)
+		value.Sub(value, bigOne)
+	}
+	if value.IsUint64() {
+		return e.writeInteger(major, value.Uint64())
+	}
+	if err := e.writeTag(tag); err != nil {
+		return err
+	}
+	return e.writeByteString(value.Bytes())
+}
+
+// negativeBigInt returns the negative integer -1 - n
+func negativeBigInt(n uint64) *big.Int {
+	var i = new(big.Int).SetUint64(n)
+	return i.Sub(i.Neg(i), bigOne)
+}
+
+// readBigInt reads an integer or the content of a bignum
+func (d *Decoder) readBigInt(tag uint64, major, minor byte, v reflect.Value) (*big.Int, error) {
+	switch {
+	case tag == noTag && major == majorPositiveInteger:
+		n, err := d.readArgument(minor)
+		return new(big.Int).SetUint64(n), err
+	case tag == noTag && major == majorNegativeInteger:
+		n, err := d.readArgument(minor)
+		return negativeBigInt(n), err
+	case (tag == tagPositiveBignum || tag == tagNegativeBignum) && major == majorByteString:
+		b, err := d.readString(major, minor)
+		if err != nil {
+			return nil, err
+		}
+		var i = new(big.Int).SetBytes(b)
+		if tag == tagNegativeBignum {
+			i.Sub(i.Neg(i), bigOne)
+		}
+		return i, nil
+	}
+	return nil, d.typeError(major, minor, v)
+}
+
+// decodeBigInt decodes an integer or a bignum into the big.Int v
+func (d *Decoder) decodeBigInt(tag uint64, major, minor byte, v reflect.Value) error {
+	i, err := d.readBigInt(tag, major, minor, v)
+	if err != nil {
+		return err
+	}
+	v.Set(reflect.ValueOf(*i))
+	return nil
+}
+
+// decodeBignumInteger decodes a bignum into v, an integer or an empty
+// interface. The bignum must fit in v.
+func (d *Decoder) decodeBignumInteger(tag uint64, major, minor byte, v reflect.Value) error {
+	i, err := d.readBigInt(tag, major, minor, v)
+	if err != nil {
+		return err
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i.IsInt64() && !v.OverflowInt(i.Int64()) {
+			v.SetInt(i.Int64())
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i.IsUint64() && !v.OverflowUint(i.Uint64()) {
+			v.SetUint(i.Uint64())
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		var f, _ = new(big.Float).SetInt(i).Float64()
+		if !math.IsInf(f, 0) && !v.OverflowFloat(f) {
+			v.SetFloat(f)
+			return nil
+		}
+	default:
+		return &UnmarshalTypeError{Value: "bignum", Type: v.Type()}
+	}
+	return fmt.Errorf("cbor: bignum %s overflows Go value of type %s", i, v.Type())
+}
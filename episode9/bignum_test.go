@@ -0,0 +1,72 @@
+package cbor
+
+import (
+	"math/big"
+	"testing"
+)
+
+func bigIntFromString(s string) *big.Int {
+	var i, _ = new(big.Int).SetString(s, 10)
+	return i
+}
+
+func TestBigInt(t *testing.T) {
+	var cases = []struct {
+		Value    *big.Int
+		Expected []byte
+	}{
+		{Value: big.NewInt(0), Expected: []byte{0x00}},
+		{Value: big.NewInt(1000), Expected: []byte{0x19, 0x03, 0xe8}},
+		{Value: big.NewInt(-1000), Expected: []byte{0x39, 0x03, 0xe7}},
+		{
+			Value:    bigIntFromString("18446744073709551615"),
+			Expected: []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+		{
+			Value:    bigIntFromString("-18446744073709551616"),
+			Expected: []byte{0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+		{
+			Value: bigIntFromString("18446744073709551616"),
+			Expected: []byte{
+				0xc2, 0x49, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			},
+		},
+		{
+			Value: bigIntFromString("-18446744073709551617"),
+			Expected: []byte{
+				0xc3, 0x49, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.Value.String(), func(t *testing.T) {
+			testEncoder(t, c.Value, c.Expected)
+			testEncoder(t, *c.Value, c.Expected)
+			testDecoder(t, c.Expected, c.Value)
+			testDecoder(t, c.Expected, *c.Value)
+		})
+	}
+}
+
+func TestBignumDecode(t *testing.T) {
+	// bignums that fit in integers
+	testDecoder(t, []byte{0xc2, 0x42, 0x01, 0x00}, uint64(256))
+	testDecoder(t, []byte{0xc3, 0x42, 0x01, 0x00}, int64(-257))
+	testDecoder(t, []byte{0xc2, 0x40}, int8(0))
+	var i8 int8
+	testDecoderError(t, []byte{0xc2, 0x42, 0x01, 0x00}, &i8)
+	var u uint64
+	testDecoderError(t, []byte{0xc3, 0x40}, &u)
+	testDecoderError(t, []byte{0xc2, 0x01}, &u)
+
+	// into interface{}
+	testDecoder(t,
+		[]byte{0x82, 0xc2, 0x49, 0x01, 0, 0, 0, 0, 0, 0, 0, 0, 0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		[]interface{}{
+			bigIntFromString("18446744073709551616"),
+			bigIntFromString("-18446744073709551616"),
+		},
+	)
+}
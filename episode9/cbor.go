@@ -1,26 +1,110 @@
-// Implements CBOR encoding:
+// Implements CBOR encoding and decoding:
 //
 //   https://tools.ietf.org/html/rfc7049
 //
 package cbor
 
 import (
+	"encoding/base64"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
 	"math"
+	"math/big"
 	"math/bits"
+	"net"
+	"net/mail"
+	"net/netip"
+	"net/url"
 	"reflect"
+	"regexp"
+	"time"
+	"unsafe"
 )
 
 type Encoder struct {
-	w io.Writer
+	w              io.Writer
+	timeFormat     TimeFormat
+	durationFormat DurationFormat
+	nilContainers  NilContainers
+	invalidUTF8    InvalidUTF8
+	selfDescribe   bool
+	typedArrays    bool
+	matrices       bool
+	valueSharing   bool
+
+	// pointers found more than once in the value being encoded when value
+	// sharing is enabled, and the number of shared values written
+	shared      map[sharedKey]int
+	sharedCount int
+
+	// maximum and current nesting depth, and the pointers, maps, and slices
+	// being encoded deeper than startDetectingCyclesAfter to detect cycles
+	maxDepth int
+	depth    int
+	path     map[pathKey]struct{}
+
+	// strings of the namespace when string references are enabled
+	stringRefs   bool
+	namespace    map[stringRefKey]uint64
+	namespaceLen uint64
+
+	mapsAsSets bool
 }
 
 func NewEncoder(w io.Writer) *Encoder {
 	return &Encoder{w: w}
 }
 
+// SetSelfDescribe enables or disables the self-describe tag 55799 in front of
+// each value written by Encode, it makes CBOR files easy to identify
+func (e *Encoder) SetSelfDescribe(enabled bool) {
+	e.selfDescribe = enabled
+}
+
+// SetTypedArrays enables or disables the encoding of slices and arrays of
+// numbers as RFC 8746 little endian typed arrays
+func (e *Encoder) SetTypedArrays(enabled bool) {
+	e.typedArrays = enabled
+}
+
+// SetMatrices enables or disables the encoding of nested arrays, like
+// [3][4]float64, as RFC 8746 row-major multi-dimensional arrays
+func (e *Encoder) SetMatrices(enabled bool) {
+	e.matrices = enabled
+}
+
+// SetValueSharing enables or disables value sharing: pointers found more than
+// once in a value are written once with the tag 28, and referenced with the
+// tag 29 after that. Cyclic pointers can be encoded with value sharing.
+func (e *Encoder) SetValueSharing(enabled bool) {
+	e.valueSharing = enabled
+}
+
+// NilContainers selects how the encoder writes nil slices and nil maps
+type NilContainers int
+
+const (
+	// NilContainersAsEmpty writes nil slices and maps like empty ones
+	NilContainersAsEmpty NilContainers = iota
+	// NilContainersAsNull writes nil slices and maps as null, that way they
+	// can be told apart from empty ones
+	NilContainersAsNull
+)
+
+// SetNilContainers selects how nil slices and maps are encoded, the default is
+// NilContainersAsEmpty
+func (e *Encoder) SetNilContainers(mode NilContainers) {
+	e.nilContainers = mode
+}
+
+// SetTimeFormat selects how time.Time values are encoded, the default is
+// TimeRFC3339
+func (e *Encoder) SetTimeFormat(f TimeFormat) {
+	e.timeFormat = f
+}
+
 var ErrNotImplemented = errors.New("Not Implemented")
 
 func (e *Encoder) writeHeader(major, minor byte) error {
@@ -53,7 +137,14 @@ func (e *Encoder) writeInteger(major byte, i uint64) error {
 	}
 }
 
+func (e *Encoder) writeTag(tag uint64) error {
+	return e.writeInteger(majorTag, tag)
+}
+
 func (e *Encoder) writeByteString(s []byte) error {
+	if done, err := e.writeStringRef(majorByteString, string(s)); done {
+		return err
+	}
 	if err := e.writeInteger(majorByteString, uint64(len(s))); err != nil {
 		return err
 	}
@@ -62,6 +153,12 @@ func (e *Encoder) writeByteString(s []byte) error {
 }
 
 func (e *Encoder) writeUnicodeString(s string) error {
+	if done, err := e.writeInvalidUTF8(s); done {
+		return err
+	}
+	if done, err := e.writeStringRef(majorUnicodeString, s); done {
+		return err
+	}
 	if err := e.writeInteger(majorUnicodeString, uint64(len(s))); err != nil {
 		return err
 	}
@@ -81,50 +178,160 @@ func (e *Encoder) writeArray(v reflect.Value) error {
 	return nil
 }
 
+// writeMap writes the map v, its keys are encoded like other values: struct
+// keys are written as maps, array keys as arrays
 func (e *Encoder) writeMap(v reflect.Value) error {
 	if err := e.writeInteger(majorMap, uint64(v.Len())); err != nil {
 		return err
 	}
 
 	for _, key := range v.MapKeys() {
-		e.encode(key)
-		e.encode(v.MapIndex(key))
+		if err := e.encode(key); err != nil {
+			return err
+		}
+		if err := e.encode(v.MapIndex(key)); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// field describes a struct field that's encoded and decoded as a map entry
+type field struct {
+	name  string
+	index int
+	opts  tagOptions
+}
+
+// structFields returns the list of fields of the struct type t that are
+// encoded. rest is the index of the map field tagged with the option rest, or
+// -1 if there's none.
+func structFields(t reflect.Type) (fields []field, rest int) {
+	rest = -1
+	for i := 0; i < t.NumField(); i++ {
+		var fType = t.Field(i)
+		var tag = fType.Tag.Get("cbor")
+		if tag == "-" {
+			continue
+		}
+		name, opts := parseTag(tag)
+		// the rest field collects the map entries that don't match a field
+		if opts.Contains("rest") && fType.Type.Kind() == reflect.Map {
+			rest = i
+			continue
+		}
+		if name == "" {
+			name = fType.Name
+		}
+		fields = append(fields, field{name: name, index: i, opts: opts})
+	}
+	return fields, rest
+}
+
+// conversionOptions are the struct tag options selecting the expected
+// conversion of byte strings to text, and their tags
+var conversionOptions = []struct {
+	Name string
+	Tag  uint64
+}{
+	{Name: "b64url", Tag: tagExpectBase64URL},
+	{Name: "b64", Tag: tagExpectBase64},
+	{Name: "hex", Tag: tagExpectBase16},
+}
+
+// isPlainByteString reports whether values of type t are encoded as untagged
+// byte strings
+func isPlainByteString(t reflect.Type) bool {
+	switch t {
+	case rawMessageType, uuidType, base64URLType, base64Type, netIPType:
+		return false
+	}
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && isByte(t.Elem())
+}
+
+// isByte reports whether slices and arrays of type t are byte strings.
+// SimpleValue is a uint8 but its slices are arrays of simple values.
+func isByte(t reflect.Type) bool {
+	return t.Kind() == reflect.Uint8 && t != simpleValueType
+}
+
+// encodeField encodes the value of a struct field. Byte strings are tagged
+// with the expected conversion to text selected by the field's options.
+func (e *Encoder) encodeField(v reflect.Value, opts tagOptions) error {
+	var x = v
+	for (x.Kind() == reflect.Ptr || x.Kind() == reflect.Interface) && !x.IsNil() {
+		x = x.Elem()
+	}
+	if x.IsValid() && isPlainByteString(x.Type()) {
+		for _, conversion := range conversionOptions {
+			if !opts.Contains(conversion.Name) {
+				continue
+			}
+			if err := e.writeTag(conversion.Tag); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return e.encode(v)
+}
+
 func (e *Encoder) writeStruct(v reflect.Value) error {
 	type fieldKeyValue struct {
 		Name  string
 		Value reflect.Value
+		Opts  tagOptions
 	}
 	var fields []fieldKeyValue
+	var names = make(map[string]bool)
+	var typeFields, rest = structFields(v.Type())
 	// Iterate over each field and add its key & value to fields
-	for i := 0; i < v.NumField(); i++ {
-		var fType = v.Type().Field(i)
-		var fValue = v.Field(i)
-		var tag = fType.Tag.Get("cbor")
-		if tag == "-" {
+	for _, f := range typeFields {
+		var fValue = v.Field(f.index)
+		names[f.name] = true
+		// with the option omitempty skip the value if it's empty
+		if f.opts.Contains("omitempty") && isEmptyValue(fValue) {
 			continue
 		}
-		name, opts := parseTag(tag)
-		// with the option omitempty skip the value if it's empty
-		if opts.Contains("omitempty") && isEmptyValue(fValue) {
+		// with the option omitzero skip the value if it's the zero value
+		if f.opts.Contains("omitzero") && isZeroValue(fValue) {
 			continue
 		}
-		if name == "" {
-			name = fType.Name
+		fields = append(fields, fieldKeyValue{Name: f.name, Value: fValue, Opts: f.opts})
+	}
+	// Entries from the rest field are written after the regular fields,
+	// entries with the same key as a field are dropped
+	var restMap reflect.Value
+	var restKeys []reflect.Value
+	if rest != -1 {
+		restMap = v.Field(rest)
+		for _, key := range restMap.MapKeys() {
+			var k = key
+			if k.Kind() == reflect.Interface {
+				k = k.Elem()
+			}
+			if k.Kind() == reflect.String && names[k.String()] {
+				continue
+			}
+			restKeys = append(restKeys, key)
 		}
-		fields = append(fields, fieldKeyValue{Name: name, Value: fValue})
 	}
-	if err := e.writeInteger(majorMap, uint64(len(fields))); err != nil {
+	if err := e.writeInteger(majorMap, uint64(len(fields)+len(restKeys))); err != nil {
 		return err
 	}
 	for _, kv := range fields {
 		if err := e.writeUnicodeString(kv.Name); err != nil {
 			return err
 		}
-		if err := e.encode(kv.Value); err != nil {
+		if err := e.encodeField(kv.Value, kv.Opts); err != nil {
+			return err
+		}
+	}
+	for _, key := range restKeys {
+		if err := e.encode(key); err != nil {
+			return err
+		}
+		if err := e.encode(restMap.MapIndex(key)); err != nil {
 			return err
 		}
 	}
@@ -215,22 +422,142 @@ func (e *Encoder) writeFloat(input float64) error {
 }
 
 func (e *Encoder) Encode(v interface{}) error {
-	return e.encode(reflect.ValueOf(v))
+	var x = reflect.ValueOf(v)
+	if x.IsValid() {
+		// Copy v into an addressable value, this way methods with pointer
+		// receivers like IsZero can be called on v and its fields
+		var n = reflect.New(x.Type()).Elem()
+		n.Set(x)
+		x = n
+	}
+	if e.selfDescribe {
+		if err := e.writeTag(tagSelfDescribe); err != nil {
+			return err
+		}
+	}
+	e.shared, e.sharedCount = nil, 0
+	e.depth, e.path = 0, nil
+	e.namespace, e.namespaceLen = nil, 0
+	if e.stringRefs {
+		if err := e.writeTag(tagStringRefNamespace); err != nil {
+			return err
+		}
+		e.namespace = make(map[stringRefKey]uint64)
+	}
+	if e.valueSharing {
+		e.shared = findShared(x, e.maxNestingDepth())
+	}
+	return e.encode(x)
+}
+
+// valueInterface returns x's value as an interface{}, including when x was
+// obtained through unexported struct fields
+func valueInterface(x reflect.Value) interface{} {
+	return exported(x).Interface()
+}
+
+// exported returns x without the read-only flag of unexported struct fields
+// when x is addressable, so that its value and elements can be read
+func exported(x reflect.Value) reflect.Value {
+	if !x.CanInterface() && x.CanAddr() {
+		x = reflect.NewAt(x.Type(), unsafe.Pointer(x.UnsafeAddr())).Elem()
+	}
+	return x
+}
+
+// addressable returns x, or an addressable copy of x when x is a map value or
+// the content of an interface, so that its unexported fields can be read
+func addressable(x reflect.Value) reflect.Value {
+	if x.CanAddr() {
+		return x
+	}
+	var n = reflect.New(x.Type()).Elem()
+	n.Set(x)
+	return n
 }
 
 func (e *Encoder) encode(x reflect.Value) error {
+	if e.depth++; e.depth > e.maxNestingDepth() {
+		e.depth--
+		return &UnsupportedValueError{x, fmt.Sprintf("exceeds the maximum depth of %d", e.maxNestingDepth())}
+	}
+	defer func() { e.depth-- }()
+
+	if x.IsValid() {
+		// types with their own encoding
+		if isEmbedded(x.Type()) {
+			return e.writeEmbedded(x)
+		}
+		if isMatrix(x.Type()) {
+			return e.writeMatrixValue(x)
+		}
+		switch x.Type() {
+		case rawMessageType:
+			return e.writeRawMessage(x.Bytes())
+		case timeType:
+			return e.writeTime(valueInterface(x).(time.Time))
+		case dateType:
+			return e.writeDate(valueInterface(x).(Date))
+		case simpleValueType:
+			return e.writeSimpleValue(SimpleValue(x.Uint()))
+		case durationType:
+			if e.durationFormat != DurationNanoseconds {
+				return e.writeDuration(time.Duration(x.Int()))
+			}
+		case bigIntType:
+			var n = valueInterface(x).(big.Int)
+			return e.writeBigInt(&n)
+		case decimalType:
+			var d = valueInterface(x).(Decimal)
+			return e.writeFraction(tagDecimalFraction, d.Exponent, &d.Mantissa)
+		case bigFloatType:
+			var f = valueInterface(x).(BigFloat)
+			return e.writeFraction(tagBigFloat, f.Exponent, &f.Mantissa)
+		case urlType:
+			var u = valueInterface(x).(url.URL)
+			return e.writeTaggedString(tagURI, u.String())
+		case regexpType:
+			var r = valueInterface(x).(regexp.Regexp)
+			return e.writeTaggedString(tagRegexp, r.String())
+		case mailMessageType:
+			var m = valueInterface(x).(mail.Message)
+			return e.writeMailMessage(&m)
+		case uuidType:
+			return e.writeUUID(valueInterface(x).(UUID))
+		case base64URLType:
+			var s = base64.RawURLEncoding.EncodeToString(x.Bytes())
+			return e.writeTaggedString(tagBase64URL, s)
+		case base64Type:
+			var s = base64.StdEncoding.EncodeToString(x.Bytes())
+			return e.writeTaggedString(tagBase64, s)
+		case netipAddrType:
+			return e.writeAddr(valueInterface(x).(netip.Addr))
+		case netipPrefixType:
+			return e.writePrefix(valueInterface(x).(netip.Prefix))
+		case netIPType:
+			return e.writeIP(net.IP(x.Bytes()))
+		case netIPNetType:
+			return e.writeIPNet(valueInterface(x).(net.IPNet))
+		}
+	}
 	switch x.Kind() {
 	case reflect.Invalid:
 		// naked nil value == invalid type
 		return e.writeHeader(majorSimpleValue, simpleValueNil)
 	case reflect.Interface:
-		return e.encode(x.Elem())
+		return e.encode(exported(x).Elem())
 	case reflect.Ptr:
 		if x.IsNil() {
 			return e.writeHeader(majorSimpleValue, simpleValueNil)
-		} else {
-			return e.encode(reflect.Indirect(x))
 		}
+		if done, err := e.writeShared(x); done {
+			return err
+		}
+		if err := e.enter(x); err != nil {
+			return err
+		}
+		defer e.leave(x)
+		return e.encode(reflect.Indirect(x))
 	case reflect.Bool:
 		var minor byte
 		if x.Bool() {
@@ -249,24 +576,54 @@ func (e *Encoder) encode(x reflect.Value) error {
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		return e.writeInteger(majorPositiveInteger, x.Uint())
 	case reflect.Array:
+		if dims, elem := arrayShape(x.Type()); e.matrices && len(dims) > 1 {
+			return e.writeArrayMatrix(x, dims, elem)
+		}
 		// Create slice from array
 		var n = reflect.New(x.Type())
 		n.Elem().Set(x)
 		x = reflect.Indirect(n).Slice(0, x.Len())
 		fallthrough
 	case reflect.Slice:
-		if x.Type().Elem().Kind() == reflect.Uint8 {
+		if x.IsNil() && e.nilContainers == NilContainersAsNull {
+			return e.writeHeader(majorSimpleValue, simpleValueNil)
+		}
+		if isByte(x.Type().Elem()) {
 			return e.writeByteString(x.Bytes())
 		}
+		if tag, ok := e.typedArrayTag(x.Type().Elem()); ok {
+			return e.writeTypedArray(tag, x)
+		}
+		if x.Len() > 0 {
+			if err := e.enter(x); err != nil {
+				return err
+			}
+			defer e.leave(x)
+		}
 		return e.writeArray(x)
 	case reflect.String:
 		return e.writeUnicodeString(x.String())
 	case reflect.Map:
+		if x.IsNil() && e.nilContainers == NilContainersAsNull {
+			return e.writeHeader(majorSimpleValue, simpleValueNil)
+		}
+		x = exported(x)
+		if isSet(x.Type()) || (e.mapsAsSets && isSetShaped(x.Type())) {
+			return e.writeSet(x)
+		}
+		if !x.IsNil() {
+			if err := e.enter(x); err != nil {
+				return err
+			}
+			defer e.leave(x)
+		}
 		return e.writeMap(x)
 	case reflect.Struct:
-		return e.writeStruct(x)
+		return e.writeStruct(addressable(x))
 	case reflect.Float32, reflect.Float64:
 		return e.writeFloat(x.Float())
+	case reflect.Complex64, reflect.Complex128:
+		return e.writeComplex(x.Complex())
 	}
 	return ErrNotImplemented
 }
@@ -279,6 +636,7 @@ const (
 	majorUnicodeString   = 3
 	majorArray           = 4
 	majorMap             = 5
+	majorTag             = 6
 	majorSimpleValue     = 7
 
 	// extended integers
@@ -287,13 +645,65 @@ const (
 	minorInt32 = 26
 	minorInt64 = 27
 
+	// indefinite length items, and the break stop code that ends them
+	minorIndefinite = 31
+	minorBreak      = 31
+
 	// floating point types
 	minorFloat16 = 25
 	minorFloat32 = 26
 	minorFloat64 = 27
 
 	// simple values == major type 7
-	simpleValueFalse = 20
-	simpleValueTrue  = 21
-	simpleValueNil   = 22
+	simpleValueFalse     = 20
+	simpleValueTrue      = 21
+	simpleValueNil       = 22
+	simpleValueUndefined = 23
+
+	// tags == major type 6
+	tagDateTime = 0
+	tagEpoch    = 1
+	// bignums
+	tagPositiveBignum = 2
+	tagNegativeBignum = 3
+	// decimal fractions and bigfloats
+	tagDecimalFraction = 4
+	tagBigFloat        = 5
+	// expected conversions of byte strings to text
+	tagExpectBase64URL = 21
+	tagExpectBase64    = 22
+	tagExpectBase16    = 23
+	// embedded CBOR data item
+	tagEmbedded = 24
+	// string references
+	tagStringRef          = 25
+	tagStringRefNamespace = 256
+	// value sharing
+	tagShareable = 28
+	tagSharedRef = 29
+	// standard tags for text strings and UUIDs
+	tagURI       = 32
+	tagBase64URL = 33
+	tagBase64    = 34
+	tagRegexp    = 35
+	tagMIME      = 36
+	tagUUID      = 37
+	// multi-dimensional arrays
+	tagMultiDimArray            = 40
+	tagMultiDimArrayColumnMajor = 1040
+	// network addresses and prefixes
+	tagIPv4 = 52
+	tagIPv6 = 54
+	// dates
+	tagDays     = 100
+	tagFullDate = 1004
+	// sets
+	tagSet = 258
+	// extended time and duration
+	tagExtendedTime = 1001
+	tagDuration     = 1002
+	// complex numbers
+	tagComplex = 43000
+	// self-describe CBOR, its encoding is 0xd9d9f7
+	tagSelfDescribe = 55799
 )
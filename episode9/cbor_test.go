@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 	"testing"
+	"time"
 )
 
 // testEncoder test the CBOR encoder with the value v, and verify that err, and
@@ -331,6 +332,42 @@ func TestStructTag(t *testing.T) {
 	)
 }
 
+type zeroer struct {
+	v int
+}
+
+func (z *zeroer) IsZero() bool {
+	return z.v == 42
+}
+
+func TestStructTagOmitZero(t *testing.T) {
+	type point struct {
+		X, Y int
+	}
+	testEncoder(t,
+		struct {
+			AField int       `cbor:"a"`
+			Point  point     `cbor:"p,omitzero"`
+			Time   time.Time `cbor:"t,omitzero"`
+			Ptr    *int      `cbor:"ptr,omitzero"`
+			Custom zeroer    `cbor:"z,omitzero"`
+			Both   []int     `cbor:"b,omitempty,omitzero"`
+		}{AField: 1, Custom: zeroer{v: 42}, Both: []int{}},
+		[]byte{0xa1, 0x61, 0x61, 0x01},
+	)
+	// non-zero values are kept
+	testEncoder(t,
+		struct {
+			Point point `cbor:"p,omitzero"`
+			Empty []int `cbor:"e,omitzero"`
+		}{Point: point{Y: 1}, Empty: []int{}},
+		[]byte{
+			0xa2, 0x61, 0x70, 0xa2, 0x61, 0x58, 0x00, 0x61, 0x59, 0x01,
+			0x61, 0x65, 0x80,
+		},
+	)
+}
+
 func TestFloat(t *testing.T) {
 	var cases = []struct {
 		Value    float64
@@ -366,3 +403,70 @@ func TestFloat(t *testing.T) {
 		})
 	}
 }
+
+func TestStructTagConversion(t *testing.T) {
+	var data = []byte{1, 2}
+	testEncoder(t,
+		struct {
+			URL    []byte  `cbor:"u,b64url"`
+			Std    [2]byte `cbor:"s,b64"`
+			Hex    *[]byte `cbor:"h,hex"`
+			Nil    *[]byte `cbor:"n,hex"`
+			Ignore int     `cbor:"i,hex"`
+		}{URL: data, Std: [2]byte{1, 2}, Hex: &data, Ignore: 1},
+		[]byte{
+			0xa5,
+			0x61, 0x75, 0xd5, 0x42, 0x01, 0x02,
+			0x61, 0x73, 0xd6, 0x42, 0x01, 0x02,
+			0x61, 0x68, 0xd7, 0x42, 0x01, 0x02,
+			0x61, 0x6e, 0xf6,
+			0x61, 0x69, 0x01,
+		},
+	)
+}
+
+func TestSelfDescribe(t *testing.T) {
+	var buffer bytes.Buffer
+	var e = NewEncoder(&buffer)
+	e.SetSelfDescribe(true)
+	for _, v := range []interface{}{1, []int{2}} {
+		if err := e.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var expected = []byte{0xd9, 0xd9, 0xf7, 0x01, 0xd9, 0xd9, 0xf7, 0x81, 0x02}
+	if !bytes.Equal(buffer.Bytes(), expected) {
+		t.Fatalf("%#v != %#v", buffer.Bytes(), expected)
+	}
+}
+
+func TestNilContainers(t *testing.T) {
+	type S struct {
+		A []int          `cbor:"a"`
+		M map[string]int `cbor:"m"`
+		B []byte         `cbor:"b"`
+	}
+	testEncoder(t, S{}, []byte{0xa3, 0x61, 0x61, 0x80, 0x61, 0x6d, 0xa0, 0x61, 0x62, 0x40})
+
+	var buffer bytes.Buffer
+	var e = NewEncoder(&buffer)
+	e.SetNilContainers(NilContainersAsNull)
+	for _, v := range []interface{}{
+		S{},
+		S{A: []int{}, M: map[string]int{}, B: []byte{}},
+	} {
+		if err := e.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var null = []byte{0xa3, 0x61, 0x61, 0xf6, 0x61, 0x6d, 0xf6, 0x61, 0x62, 0xf6}
+	var empty = []byte{0xa3, 0x61, 0x61, 0x80, 0x61, 0x6d, 0xa0, 0x61, 0x62, 0x40}
+	if expected := append(append([]byte{}, null...), empty...); !bytes.Equal(buffer.Bytes(), expected) {
+		t.Fatalf("%#v != %#v", buffer.Bytes(), expected)
+	}
+
+	// null is decoded as nil, empty containers aren't nil
+	testDecoder(t, null, S{})
+	testDecoder(t, empty, S{A: []int{}, M: map[string]int{}, B: []byte{}})
+	testDecoder(t, []byte{0x82, 0xf6, 0x80}, []interface{}{nil, []interface{}{}})
+}
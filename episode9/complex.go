@@ -0,0 +1,42 @@
+package cbor
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// writeComplex writes c as an array of its real and imaginary parts with the
+// tag 43000, the parts are written like other floats
+func (e *Encoder) writeComplex(c complex128) error {
+	if err := e.writeTag(tagComplex); err != nil {
+		return err
+	}
+	if err := e.writeInteger(majorArray, 2); err != nil {
+		return err
+	}
+	if err := e.writeFloat(real(c)); err != nil {
+		return err
+	}
+	return e.writeFloat(imag(c))
+}
+
+// decodeComplex decodes an array of the real and imaginary parts of a complex
+// number into v. The tag 43000 is optional, tag is noTag when it's missing.
+func (d *Decoder) decodeComplex(tag uint64, major, minor byte, v reflect.Value) error {
+	if (tag != noTag && tag != tagComplex) || major != majorArray {
+		return d.typeError(major, minor, v)
+	}
+	var parts []float64
+	if err := d.decodeArray(minor, reflect.ValueOf(&parts).Elem()); err != nil {
+		return err
+	}
+	if len(parts) != 2 {
+		return fmt.Errorf("cbor: complex number with %d parts", len(parts))
+	}
+	var c = complex(parts[0], parts[1])
+	if v.OverflowComplex(c) {
+		return fmt.Errorf("cbor: complex number %v overflows Go value of type %s", c, v.Type())
+	}
+	v.SetComplex(c)
+	return nil
+}
@@ -0,0 +1,27 @@
+package cbor
+
+import (
+	"testing"
+)
+
+func TestComplex(t *testing.T) {
+	var encoded = []byte{0xd9, 0xa7, 0xf8, 0x82, 0xf9, 0x3e, 0x00, 0xf9, 0xc0, 0x00}
+	testEncoder(t, complex(1.5, -2), encoded)
+	testEncoder(t, complex64(complex(1.5, -2)), encoded)
+	testEncoder(t, complex(0.1, 0), []byte{
+		0xd9, 0xa7, 0xf8, 0x82,
+		0xfb, 0x3f, 0xb9, 0x99, 0x99, 0x99, 0x99, 0x99, 0x9a, 0xf9, 0x00, 0x00,
+	})
+
+	testDecoder(t, encoded, complex(1.5, -2))
+	testDecoder(t, encoded, complex64(complex(1.5, -2)))
+	// without tag, with integers
+	testDecoder(t, []byte{0x82, 0x01, 0x21}, complex(1, -2))
+	// into interface{}
+	testDecoder(t, []byte{0x81, 0xd9, 0xa7, 0xf8, 0x82, 0x01, 0x21}, []interface{}{complex(1, -2)})
+
+	var c complex64
+	testDecoderError(t, []byte{0xd9, 0xa7, 0xf8, 0x81, 0x01}, &c)
+	testDecoderError(t, []byte{0xd9, 0xa7, 0xf8, 0x01}, &c)
+	testDecoderError(t, []byte{0x82, 0xfb, 0x7f, 0xef, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00}, &c)
+}
@@ -0,0 +1,83 @@
+package cbor
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// DefaultMaxDepth is the default maximum nesting depth of the encoder and the
+// decoder
+const DefaultMaxDepth = 10000
+
+// startDetectingCyclesAfter is the nesting depth from which the encoder tracks
+// the pointers, maps, and slices being encoded. A cycle nests without end so
+// it's still found, and values nested less deeply don't pay for the tracking.
+// encoding/json uses the same threshold.
+const startDetectingCyclesAfter = 1000
+
+// UnsupportedValueError is returned by Encode when it can't encode a value,
+// like a cyclic value or a value nested too deeply.
+type UnsupportedValueError struct {
+	Value reflect.Value
+	Str   string
+}
+
+func (e *UnsupportedValueError) Error() string {
+	return "cbor: unsupported value: " + e.Str
+}
+
+// pathKey identifies a pointer, map, or slice being encoded. Slices need
+// their length since a slice and its sub-slices have the same address.
+type pathKey struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
+
+func newPathKey(x reflect.Value) pathKey {
+	var key = pathKey{ptr: x.Pointer(), typ: x.Type()}
+	if x.Kind() == reflect.Slice {
+		key.len = x.Len()
+	}
+	return key
+}
+
+// SetMaxDepth sets the maximum nesting depth of values, pointers and
+// interfaces count as a level. Encode returns an error for values nested
+// deeper than that. If n <= 0 DefaultMaxDepth is used.
+func (e *Encoder) SetMaxDepth(n int) {
+	e.maxDepth = n
+}
+
+func (e *Encoder) maxNestingDepth() int {
+	if e.maxDepth <= 0 {
+		return DefaultMaxDepth
+	}
+	return e.maxDepth
+}
+
+// enter marks the pointer, map, or slice x as being encoded, an error is
+// returned if x is already being encoded: x contains itself. Values are only
+// tracked beyond startDetectingCyclesAfter.
+func (e *Encoder) enter(x reflect.Value) error {
+	if e.depth <= startDetectingCyclesAfter {
+		return nil
+	}
+	var key = newPathKey(x)
+	if _, ok := e.path[key]; ok {
+		return &UnsupportedValueError{x, fmt.Sprintf("encountered a cycle via %s", x.Type())}
+	}
+	if e.path == nil {
+		e.path = make(map[pathKey]struct{})
+	}
+	e.path[key] = struct{}{}
+	return nil
+}
+
+// leave marks x as encoded, see enter
+func (e *Encoder) leave(x reflect.Value) {
+	if e.depth <= startDetectingCyclesAfter {
+		return
+	}
+	delete(e.path, newPathKey(x))
+}
@@ -0,0 +1,87 @@
+package cbor
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func testEncoderUnsupported(t *testing.T, e *Encoder, v interface{}) *UnsupportedValueError {
+	t.Helper()
+	var err = e.Encode(v)
+	var unsupported *UnsupportedValueError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("expected UnsupportedValueError, got %#v", err)
+	}
+	return unsupported
+}
+
+func testEncoderCycle(t *testing.T, e *Encoder, v interface{}) {
+	t.Helper()
+	if err := testEncoderUnsupported(t, e, v); !strings.Contains(err.Str, "cycle") {
+		t.Fatalf("%q isn't a cycle", err.Str)
+	}
+}
+
+func TestEncodeCycle(t *testing.T) {
+	type Node struct {
+		Next *Node
+	}
+	var n = &Node{}
+	n.Next = n
+	testEncoderCycle(t, NewEncoder(io.Discard), n)
+
+	var m = map[string]interface{}{}
+	m["m"] = m
+	testEncoderCycle(t, NewEncoder(io.Discard), m)
+
+	var s = []interface{}{nil}
+	s[0] = s
+	testEncoderCycle(t, NewEncoder(io.Discard), s)
+
+	// with value sharing cyclic pointers are references, but maps and slices
+	// can't be shared
+	var e = NewEncoder(io.Discard)
+	e.SetValueSharing(true)
+	if err := e.Encode(n); err != nil {
+		t.Fatal(err)
+	}
+	testEncoderCycle(t, e, m)
+	testEncoderCycle(t, e, s)
+
+	// the same value found twice isn't a cycle
+	var i = 1
+	testEncoder(t, []*int{&i, &i}, []byte{0x82, 0x01, 0x01})
+	var sub = []interface{}{1, nil}
+	sub[1] = sub[:1]
+	testEncoder(t, sub, []byte{0x82, 0x01, 0x81, 0x01})
+
+	// shallow values aren't tracked
+	e = NewEncoder(io.Discard)
+	if err := e.Encode(map[string][]*int{"a": {&i}}); err != nil || e.path != nil {
+		t.Fatalf("%v, %#v", err, e.path)
+	}
+}
+
+func TestEncodeMaxDepth(t *testing.T) {
+	var v interface{} = 1
+	for i := 0; i < 10; i++ {
+		v = []interface{}{v}
+	}
+
+	var e = NewEncoder(io.Discard)
+	e.SetMaxDepth(10)
+	testEncoderUnsupported(t, e, v)
+	e.SetMaxDepth(100)
+	if err := e.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+
+	// the default depth is enough for most values
+	var deep interface{}
+	for i := 0; i < DefaultMaxDepth; i++ {
+		deep = []interface{}{deep}
+	}
+	testEncoderUnsupported(t, NewEncoder(io.Discard), deep)
+}
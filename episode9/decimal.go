@@ -0,0 +1,243 @@
+package cbor
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+)
+
+// Decimal is a decimal fraction, its value is Mantissa × 10^Exponent. It's
+// encoded with the tag 4.
+type Decimal struct {
+	Exponent int64
+	Mantissa big.Int
+}
+
+// BigFloat is a binary floating point number, its value is Mantissa ×
+// 2^Exponent. It's encoded with the tag 5.
+type BigFloat struct {
+	Exponent int64
+	Mantissa big.Int
+}
+
+var (
+	decimalType  = reflect.TypeOf(Decimal{})
+	bigFloatType = reflect.TypeOf(BigFloat{})
+)
+
+var ErrInexact = errors.New("cbor: number can't be represented exactly")
+
+// ErrExponentRange is returned when converting a decimal fraction or a bigfloat
+// with an exponent larger than MaxRatExponent in absolute value
+var ErrExponentRange = errors.New("cbor: exponent out of range")
+
+// MaxRatExponent is the largest exponent, in absolute value, of the decimal
+// fractions and bigfloats converted to big.Rat, it bounds the size of the
+// result
+const MaxRatExponent = 1 << 16
+
+var (
+	bigTwo  = big.NewInt(2)
+	bigFive = big.NewInt(5)
+	bigTen  = big.NewInt(10)
+)
+
+// Rat returns the exact value of x. ErrExponentRange is returned if the
+// exponent is beyond MaxRatExponent.
+func (x *Decimal) Rat() (*big.Rat, error) {
+	if !ratExponent(x.Exponent) {
+		return nil, ErrExponentRange
+	}
+	var r = new(big.Rat).SetInt(&x.Mantissa)
+	var p = new(big.Int).Exp(bigTen, big.NewInt(abs(x.Exponent)), nil)
+	if x.Exponent < 0 {
+		return r.Quo(r, new(big.Rat).SetInt(p)), nil
+	}
+	return r.Mul(r, new(big.Rat).SetInt(p)), nil
+}
+
+// Float returns the value of x rounded to the nearest big.Float.
+// ErrExponentRange is returned if the exponent is beyond MaxRatExponent.
+func (x *Decimal) Float() (*big.Float, error) {
+	var r, err = x.Rat()
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Float).SetRat(r), nil
+}
+
+// SetRat sets x to r. ErrInexact is returned if r can't be represented by a
+// decimal fraction, like 1/3.
+func (x *Decimal) SetRat(r *big.Rat) error {
+	// r can be represented if its denominator divides a power of 10, in
+	// other words if it's only made of 2s and 5s
+	var (
+		denom    = new(big.Int).Set(r.Denom())
+		twos     = int64(0)
+		fives    = int64(0)
+		quotient = new(big.Int)
+		mod      = new(big.Int)
+	)
+	for {
+		if quotient.QuoRem(denom, bigTwo, mod); mod.Sign() != 0 {
+			break
+		}
+		denom.Set(quotient)
+		twos++
+	}
+	for {
+		if quotient.QuoRem(denom, bigFive, mod); mod.Sign() != 0 {
+			break
+		}
+		denom.Set(quotient)
+		fives++
+	}
+	if denom.Cmp(bigOne) != 0 {
+		return ErrInexact
+	}
+	var exp = twos
+	if fives > exp {
+		exp = fives
+	}
+	// Mantissa = r × 10^exp
+	var m = new(big.Int).Exp(bigTen, big.NewInt(exp), nil)
+	m.Mul(m, r.Num())
+	x.Mantissa.Quo(m, r.Denom())
+	x.Exponent = -exp
+	return nil
+}
+
+// SetFloat sets x to the exact value of f. f can't be infinite.
+func (x *Decimal) SetFloat(f *big.Float) error {
+	if f.IsInf() {
+		return ErrInexact
+	}
+	var r, _ = f.Rat(nil)
+	return x.SetRat(r)
+}
+
+// Rat returns the exact value of x. ErrExponentRange is returned if the
+// exponent is beyond MaxRatExponent.
+func (x *BigFloat) Rat() (*big.Rat, error) {
+	if !ratExponent(x.Exponent) {
+		return nil, ErrExponentRange
+	}
+	var r = new(big.Rat).SetInt(&x.Mantissa)
+	var p = new(big.Int).Lsh(bigOne, uint(abs(x.Exponent)))
+	if x.Exponent < 0 {
+		return r.Quo(r, new(big.Rat).SetInt(p)), nil
+	}
+	return r.Mul(r, new(big.Rat).SetInt(p)), nil
+}
+
+// Float returns the value of x as a big.Float, with enough precision to
+// represent it exactly. Exponents beyond the range of big.Float give ±Inf or
+// ±0.
+func (x *BigFloat) Float() *big.Float {
+	var f = new(big.Float).SetInt(&x.Mantissa)
+	// the exponents of big.Float fit in an int32, larger ones overflow to
+	// ±Inf and smaller ones underflow to ±0
+	switch {
+	case f.Sign() == 0:
+		return f
+	case x.Exponent > math.MaxInt32:
+		return f.SetInf(f.Sign() < 0)
+	case x.Exponent < math.MinInt32:
+		return f.Mul(f, new(big.Float))
+	}
+	return f.SetMantExp(f, int(x.Exponent))
+}
+
+// SetRat sets x to r. ErrInexact is returned if the denominator of r isn't a
+// power of 2.
+func (x *BigFloat) SetRat(r *big.Rat) error {
+	var denom = r.Denom()
+	var zeros = denom.TrailingZeroBits()
+	if denom.BitLen() != int(zeros)+1 {
+		return ErrInexact
+	}
+	x.Mantissa.Set(r.Num())
+	x.Exponent = -int64(zeros)
+	return nil
+}
+
+// SetFloat sets x to the exact value of f. f can't be infinite.
+func (x *BigFloat) SetFloat(f *big.Float) error {
+	if f.IsInf() {
+		return ErrInexact
+	}
+	if f.Sign() == 0 {
+		x.Mantissa.SetInt64(0)
+		x.Exponent = 0
+		return nil
+	}
+	// f = mantissa × 2^exp with 0.5 <= |mantissa| < 1, shift the mantissa to
+	// turn it into an integer
+	var mantissa = new(big.Float)
+	var exp = f.MantExp(mantissa)
+	var prec = int(f.MinPrec())
+	mantissa.SetMantExp(mantissa, prec)
+	mantissa.Int(&x.Mantissa)
+	x.Exponent = int64(exp - prec)
+	return nil
+}
+
+// ratExponent reports whether the exponent e is small enough to convert a
+// fraction to big.Rat
+func ratExponent(e int64) bool {
+	return e >= -MaxRatExponent && e <= MaxRatExponent
+}
+
+// abs returns the absolute value of i, which can't be math.MinInt64
+func abs(i int64) int64 {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+// writeFraction writes a decimal fraction or a bigfloat as a tagged array of
+// two integers: the exponent and the mantissa
+func (e *Encoder) writeFraction(tag uint64, exponent int64, mantissa *big.Int) error {
+	if err := e.writeTag(tag); err != nil {
+		return err
+	}
+	if err := e.writeInteger(majorArray, 2); err != nil {
+		return err
+	}
+	if err := e.encode(reflect.ValueOf(exponent)); err != nil {
+		return err
+	}
+	return e.writeBigInt(mantissa)
+}
+
+// decodeFraction decodes a decimal fraction or a bigfloat with the tag
+// expected into the exponent and the mantissa of v. The mantissa can be an
+// integer or a bignum.
+func (d *Decoder) decodeFraction(tag, expected uint64, major, minor byte, v reflect.Value, exponent *int64, mantissa *big.Int) error {
+	if (tag != noTag && tag != expected) || major != majorArray {
+		return d.typeError(major, minor, v)
+	}
+	var i = 0
+	err := d.readItems(minor, func(major, minor byte) error {
+		i++
+		switch i {
+		case 1:
+			if major != majorPositiveInteger && major != majorNegativeInteger {
+				return d.typeError(major, minor, v)
+			}
+			return d.decodeInteger(major, minor, reflect.ValueOf(exponent).Elem())
+		case 2:
+			return d.decodeValue(major, minor, reflect.ValueOf(mantissa).Elem())
+		}
+		return ErrMalformed
+	})
+	if err != nil {
+		return err
+	}
+	if i != 2 {
+		return ErrMalformed
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package cbor
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestDecimal(t *testing.T) {
+	// 273.15
+	var d Decimal
+	d.Exponent = -2
+	d.Mantissa.SetInt64(27315)
+	var encoded = []byte{0xc4, 0x82, 0x21, 0x19, 0x6a, 0xb3}
+	testEncoder(t, d, encoded)
+	testDecoder(t, encoded, d)
+	testDecoder(t, encoded, &d)
+	testDecoder(t, append([]byte{0x81}, encoded...), []interface{}{&d})
+
+	if r, err := d.Rat(); err != nil || r.Cmp(big.NewRat(27315, 100)) != 0 {
+		t.Fatalf("%v != 273.15", r)
+	}
+
+	// bignum mantissa
+	var b Decimal
+	b.Exponent = 1
+	b.Mantissa.SetString("-18446744073709551617", 10)
+	testEncoder(t, b, []byte{
+		0xc4, 0x82, 0x01,
+		0xc3, 0x49, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	})
+	testDecoder(t, []byte{
+		0xc4, 0x82, 0x01,
+		0xc3, 0x49, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}, b)
+
+	var x Decimal
+	testDecoderError(t, []byte{0xc4, 0x81, 0x01}, &x)
+	testDecoderError(t, []byte{0xc5, 0x82, 0x01, 0x01}, &x)
+	testDecoderError(t, []byte{0xc4, 0x82, 0xf9, 0x3c, 0x00, 0x01}, &x)
+}
+
+func TestDecimalConversion(t *testing.T) {
+	var d Decimal
+	if err := d.SetRat(big.NewRat(-3, 8)); err != nil {
+		t.Fatal(err)
+	}
+	if d.Exponent != -3 || d.Mantissa.Int64() != -375 {
+		t.Fatalf("%v × 10^%v != -0.375", &d.Mantissa, d.Exponent)
+	}
+	if err := d.SetRat(big.NewRat(1, 3)); err != ErrInexact {
+		t.Fatalf("%#v != ErrInexact", err)
+	}
+	if err := d.SetFloat(big.NewFloat(2.5)); err != nil {
+		t.Fatal(err)
+	}
+	if f, err := d.Float(); err != nil {
+		t.Fatal(err)
+	} else if x, _ := f.Float64(); x != 2.5 {
+		t.Fatalf("%v != 2.5", x)
+	}
+
+	// huge exponents aren't converted
+	for _, exponent := range []int64{MaxRatExponent + 1, -MaxRatExponent - 1, math.MinInt64} {
+		d.Exponent = exponent
+		if _, err := d.Rat(); err != ErrExponentRange {
+			t.Fatalf("%#v != ErrExponentRange", err)
+		}
+		if _, err := d.Float(); err != ErrExponentRange {
+			t.Fatalf("%#v != ErrExponentRange", err)
+		}
+		var f = BigFloat{Exponent: exponent}
+		if _, err := f.Rat(); err != ErrExponentRange {
+			t.Fatalf("%#v != ErrExponentRange", err)
+		}
+	}
+}
+
+func TestBigFloat(t *testing.T) {
+	// 1.5
+	var f BigFloat
+	f.Exponent = -1
+	f.Mantissa.SetInt64(3)
+	var encoded = []byte{0xc5, 0x82, 0x20, 0x03}
+	testEncoder(t, f, encoded)
+	testDecoder(t, encoded, f)
+
+	if x, _ := f.Float().Float64(); x != 1.5 {
+		t.Fatalf("%v != 1.5", x)
+	}
+	if r, err := f.Rat(); err != nil || r.Cmp(big.NewRat(3, 2)) != 0 {
+		t.Fatalf("%v != 3/2", r)
+	}
+
+	var g BigFloat
+	if err := g.SetFloat(big.NewFloat(-0.375)); err != nil {
+		t.Fatal(err)
+	}
+	if g.Exponent != -3 || g.Mantissa.Int64() != -3 {
+		t.Fatalf("%v × 2^%v != -0.375", &g.Mantissa, g.Exponent)
+	}
+	if err := g.SetRat(big.NewRat(12, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := g.Rat(); err != nil || r.Cmp(big.NewRat(12, 1)) != 0 {
+		t.Fatalf("%v != 12", r)
+	}
+	if err := g.SetRat(big.NewRat(1, 10)); err != ErrInexact {
+		t.Fatalf("%#v != ErrInexact", err)
+	}
+
+	// exponents beyond big.Float's range
+	g.Mantissa.SetInt64(1)
+	g.Exponent = math.MaxInt64
+	if !g.Float().IsInf() {
+		t.Fatal("2^MaxInt64 isn't infinite")
+	}
+	g.Exponent = math.MinInt64
+	if g.Float().Sign() != 0 {
+		t.Fatal("2^MinInt64 != 0")
+	}
+}
@@ -0,0 +1,998 @@
+package cbor
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+)
+
+// Decoder reads and decodes CBOR values from an input stream
+type Decoder struct {
+	r io.Reader
+	// pushback holds the bytes put back by unread, they're read before the
+	// rest of the input
+	pushback []byte
+	// raw holds the bytes read while recording is greater than zero, it's
+	// used to capture the encoded form of items
+	raw       []byte
+	recording int
+	// values with the tag 28 decoded so far, used to resolve references
+	shared []reflect.Value
+	// strings of the current namespace, nil outside of a namespace. ref is
+	// the string returned by the last string reference, and refArgument the
+	// argument of its header.
+	namespace   []stringRef
+	ref         []byte
+	refArgument []byte
+
+	duplicateKeys DuplicateKeys
+	strictUTF8    bool
+	// nesting depth of the arrays, maps, and tags being read
+	maxDepth int
+	depth    int
+	// err is the error returned by Decode after a value was partially read
+	err error
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// SetMaxDepth sets the maximum nesting depth of items, arrays, maps, and tags
+// count as a level. Decode returns an error for items nested deeper than that.
+// If n <= 0 DefaultMaxDepth is used.
+func (d *Decoder) SetMaxDepth(n int) {
+	d.maxDepth = n
+}
+
+// enterLevel starts reading an array, a map, or the content of a tag, an error
+// is returned if it's nested too deeply
+func (d *Decoder) enterLevel() error {
+	var max = d.maxDepth
+	if max <= 0 {
+		max = DefaultMaxDepth
+	}
+	if d.depth++; d.depth > max {
+		d.depth--
+		return fmt.Errorf("cbor: exceeds the maximum depth of %d", max)
+	}
+	return nil
+}
+
+// leaveLevel ends an array, a map, or a tag, see enterLevel
+func (d *Decoder) leaveLevel() {
+	d.depth--
+}
+
+var (
+	ErrInvalidDecode = errors.New("cbor: Decode needs a non-nil pointer")
+	ErrMalformed     = errors.New("cbor: malformed input")
+)
+
+// UnmarshalTypeError describes a CBOR value that can't be decoded into a
+// specific Go type
+type UnmarshalTypeError struct {
+	Value string       // description of the CBOR value
+	Type  reflect.Type // type of the Go value it couldn't be decoded into
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	return "cbor: cannot decode " + e.Value + " into Go value of type " + e.Type.String()
+}
+
+var majorNames = [...]string{
+	majorPositiveInteger: "positive integer",
+	majorNegativeInteger: "negative integer",
+	majorByteString:      "byte string",
+	majorUnicodeString:   "text string",
+	majorArray:           "array",
+	majorMap:             "map",
+	majorTag:             "tag",
+	majorSimpleValue:     "simple value",
+}
+
+// noTag is passed to functions decoding items that may be tagged when the item
+// had no tag, it's an invalid tag number
+const noTag = math.MaxUint64
+
+// Types used when decoding into an empty interface
+var (
+	interfaceType      = reflect.TypeOf((*interface{})(nil)).Elem()
+	interfaceSliceType = reflect.TypeOf([]interface{}(nil))
+	interfaceMapType   = reflect.TypeOf(map[interface{}]interface{}(nil))
+)
+
+// readFull fills p with the bytes put back by unread followed by the next
+// bytes from the input, like io.ReadFull
+func (d *Decoder) readFull(p []byte) (int, error) {
+	var n = copy(p, d.pushback)
+	d.pushback = d.pushback[n:]
+	if n == len(p) {
+		return n, nil
+	}
+	m, err := io.ReadFull(d.r, p[n:])
+	if err == io.EOF && n > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n + m, err
+}
+
+// unread puts back b, it's read again before the rest of the input
+func (d *Decoder) unread(b []byte) {
+	d.pushback = append(b, d.pushback...)
+}
+
+// read fills p with the next bytes from the input
+func (d *Decoder) read(p []byte) error {
+	if _, err := d.readFull(p); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if d.recording > 0 {
+		d.raw = append(d.raw, p...)
+	}
+	return nil
+}
+
+// readBytes reads n bytes from the input. The bytes are read in small chunks
+// to avoid allocating a huge buffer when the length of a string is invalid.
+func (d *Decoder) readBytes(n uint64) ([]byte, error) {
+	const chunkSize = 4096
+	var b = []byte{}
+	for n > 0 {
+		var c = uint64(chunkSize)
+		if n < c {
+			c = n
+		}
+		var start = len(b)
+		b = append(b, make([]byte, c)...)
+		if err := d.read(b[start:]); err != nil {
+			return nil, err
+		}
+		n -= c
+	}
+	return b, nil
+}
+
+// readHeader reads the first byte of a data item and splits it into its major
+// type and its additional information. io.EOF is returned if the input ended
+// before the header.
+func (d *Decoder) readHeader() (major, minor byte, err error) {
+	var h [1]byte
+	if _, err = d.readFull(h[:]); err != nil {
+		return 0, 0, err
+	}
+	if d.recording > 0 {
+		d.raw = append(d.raw, h[0])
+	}
+	major, minor = h[0]>>5, h[0]&0x1f
+	// string references are replaced by the string they refer to
+	if major == majorTag && d.namespace != nil && minor >= minorInt8 && minor <= minorInt64 {
+		return d.readStringRef(minor)
+	}
+	return major, minor, nil
+}
+
+// readItemHeader reads the header of an item nested in another item, the
+// input isn't allowed to end there
+func (d *Decoder) readItemHeader() (major, minor byte, err error) {
+	major, minor, err = d.readHeader()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return
+}
+
+// readArgument reads the integer argument following a header: the value of an
+// integer, the length of a string, array, or map, or the number of a tag
+func (d *Decoder) readArgument(minor byte) (uint64, error) {
+	switch {
+	case minor <= 23:
+		return uint64(minor), nil
+	case minor == minorInt8:
+		var b [1]byte
+		err := d.read(b[:])
+		return uint64(b[0]), err
+	case minor == minorInt16:
+		var b [2]byte
+		err := d.read(b[:])
+		return uint64(binary.BigEndian.Uint16(b[:])), err
+	case minor == minorInt32:
+		var b [4]byte
+		err := d.read(b[:])
+		return uint64(binary.BigEndian.Uint32(b[:])), err
+	case minor == minorInt64:
+		var b [8]byte
+		err := d.read(b[:])
+		return binary.BigEndian.Uint64(b[:]), err
+	}
+	return 0, ErrMalformed
+}
+
+// readString reads the content of a byte string or a text string,
+// concatenating the chunks of indefinite length strings
+func (d *Decoder) readString(major, minor byte) ([]byte, error) {
+	if d.ref != nil {
+		return d.readPendingStringRef(), nil
+	}
+	if minor != minorIndefinite {
+		n, err := d.readArgument(minor)
+		if err != nil {
+			return nil, err
+		}
+		s, err := d.readBytes(n)
+		if err != nil {
+			return nil, err
+		}
+		if err := d.checkUTF8(major, s); err != nil {
+			return nil, err
+		}
+		d.addStringRef(major, s)
+		return s, nil
+	}
+	var s = []byte{}
+	for {
+		chunkMajor, chunkMinor, err := d.readItemHeader()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor == majorSimpleValue && chunkMinor == minorBreak {
+			return s, nil
+		}
+		// chunks must be definite length strings of the same type, they
+		// aren't added to namespaces
+		if chunkMajor != major || chunkMinor == minorIndefinite || d.ref != nil {
+			return nil, ErrMalformed
+		}
+		n, err := d.readArgument(chunkMinor)
+		if err != nil {
+			return nil, err
+		}
+		chunk, err := d.readBytes(n)
+		if err != nil {
+			return nil, err
+		}
+		if err := d.checkUTF8(major, chunk); err != nil {
+			return nil, err
+		}
+		s = append(s, chunk...)
+	}
+}
+
+// readItems calls f with the header of each item of an array, or each key of
+// a map. f must read the whole item, and the value following the key for maps.
+func (d *Decoder) readItems(minor byte, f func(major, minor byte) error) error {
+	if err := d.enterLevel(); err != nil {
+		return err
+	}
+	defer d.leaveLevel()
+	if minor == minorIndefinite {
+		for {
+			major, minor, err := d.readItemHeader()
+			if err != nil {
+				return err
+			}
+			if major == majorSimpleValue && minor == minorBreak {
+				return nil
+			}
+			if err := f(major, minor); err != nil {
+				return err
+			}
+		}
+	}
+	n, err := d.readArgument(minor)
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < n; i++ {
+		major, minor, err := d.readItemHeader()
+		if err != nil {
+			return err
+		}
+		if err := f(major, minor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skip reads the next item and discards it
+func (d *Decoder) skip() error {
+	major, minor, err := d.readItemHeader()
+	if err != nil {
+		return err
+	}
+	return d.skipValue(major, minor)
+}
+
+// skipValue reads the rest of the item with the given header and discards it
+func (d *Decoder) skipValue(major, minor byte) error {
+	switch major {
+	case majorPositiveInteger, majorNegativeInteger:
+		_, err := d.readArgument(minor)
+		return err
+	case majorByteString, majorUnicodeString:
+		_, err := d.readString(major, minor)
+		return err
+	case majorArray:
+		return d.readItems(minor, d.skipValue)
+	case majorMap:
+		return d.readItems(minor, func(major, minor byte) error {
+			if err := d.skipValue(major, minor); err != nil {
+				return err
+			}
+			return d.skip()
+		})
+	case majorTag:
+		tag, err := d.readArgument(minor)
+		if err != nil {
+			return err
+		}
+		if err := d.enterLevel(); err != nil {
+			return err
+		}
+		defer d.leaveLevel()
+		switch tag {
+		case tagStringRefNamespace:
+			defer d.enterNamespace()()
+		case tagShareable:
+			// keep the indices of the following shared values
+			d.shared = append(d.shared, reflect.Value{})
+		}
+		return d.skip()
+	default:
+		_, err := d.readSimpleValue(minor)
+		return err
+	}
+}
+
+// readSimpleValue reads the rest of a major type 7 item, floating point
+// numbers are returned as their raw bits
+func (d *Decoder) readSimpleValue(minor byte) (uint64, error) {
+	switch minor {
+	case minorFloat16, minorFloat32, minorFloat64:
+		return d.readArgument(minor)
+	case minorInt8:
+		var b [1]byte
+		if err := d.read(b[:]); err != nil {
+			return 0, err
+		}
+		// values below 32 must use the short form, 24 to 31 are reserved
+		if b[0] < 32 {
+			return 0, ErrMalformed
+		}
+		return uint64(b[0]), nil
+	}
+	if minor < minorInt8 {
+		return uint64(minor), nil
+	}
+	// reserved values and a break outside an indefinite length item
+	return 0, ErrMalformed
+}
+
+// float16ToFloat64 converts the bits of a 16 bits floating point number to a
+// float64
+func float16ToFloat64(h uint16) float64 {
+	var (
+		negative = h>>15 != 0
+		exp      = int(h>>float16FracBits) & ((1 << float16ExpBits) - 1)
+		frac     = uint64(h) & ((1 << float16FracBits) - 1)
+		f        float64
+	)
+	switch exp {
+	case 0:
+		// zero and subnumbers
+		f = math.Ldexp(float64(frac), float16MinBias-float16FracBits)
+	case (1 << float16ExpBits) - 1:
+		if frac == 0 {
+			f = math.Inf(1)
+		} else {
+			// keep the fractional part of the NaN
+			var bits = uint64(expMask)<<float64FracBits | frac<<(float64FracBits-float16FracBits)
+			f = math.Float64frombits(bits)
+		}
+	default:
+		f = math.Ldexp(float64(frac|1<<float16FracBits), exp-float16ExpBias-float16FracBits)
+	}
+	if negative {
+		f = math.Copysign(f, -1)
+	}
+	return f
+}
+
+// Decode reads the next CBOR encoded value from its input and stores it in the
+// value pointed to by v. io.EOF is returned when there's no more input.
+//
+// The value may be partially read when an error is returned, the input can't
+// be decoded further: the next calls to Decode return the same error.
+func (d *Decoder) Decode(v interface{}) error {
+	var x = reflect.ValueOf(v)
+	if x.Kind() != reflect.Ptr || x.IsNil() {
+		return ErrInvalidDecode
+	}
+	if d.err != nil {
+		return d.err
+	}
+	d.shared, d.namespace, d.ref, d.refArgument = nil, nil, nil, nil
+	d.depth = 0
+	major, minor, err := d.readHeader()
+	if err != nil {
+		if err != io.EOF {
+			d.err = err
+		}
+		return err
+	}
+	if err := d.decodeValue(major, minor, x.Elem()); err != nil {
+		d.err = err
+		return err
+	}
+	return nil
+}
+
+// decode reads the next item and stores it in v
+func (d *Decoder) decode(v reflect.Value) error {
+	major, minor, err := d.readItemHeader()
+	if err != nil {
+		return err
+	}
+	return d.decodeValue(major, minor, v)
+}
+
+// typeError skips the rest of the item and returns an error saying that it
+// couldn't be stored in v
+func (d *Decoder) typeError(major, minor byte, v reflect.Value) error {
+	if err := d.skipValue(major, minor); err != nil {
+		return err
+	}
+	return &UnmarshalTypeError{Value: majorNames[major], Type: v.Type()}
+}
+
+// decodeValue reads the rest of the item with the given header and stores it
+// in v
+func (d *Decoder) decodeValue(major, minor byte, v reflect.Value) error {
+	if v.Type() == rawMessageType {
+		return d.decodeRawMessage(major, minor, v)
+	}
+	// null and undefined reset pointers, interfaces, maps, and slices
+	if major == majorSimpleValue && (minor == simpleValueNil || minor == simpleValueUndefined) &&
+		v.Type() != simpleValueType {
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+	// tags are read before following pointers, references to shared values
+	// set the pointer itself. Pointers to raw messages include the tag.
+	if major == majorTag && !isRawMessagePtr(v.Type()) {
+		tag, err := d.readArgument(minor)
+		if err != nil {
+			return err
+		}
+		if err := d.enterLevel(); err != nil {
+			return err
+		}
+		defer d.leaveLevel()
+		if tag == tagStringRefNamespace {
+			defer d.enterNamespace()()
+		}
+		if major, minor, err = d.readItemHeader(); err != nil {
+			return err
+		}
+		switch tag {
+		case tagSelfDescribe, tagStringRefNamespace:
+			// the self-describe tag has no meaning, and a namespace holds
+			// the value as is, they're skipped
+			return d.decodeValue(major, minor, v)
+		case tagShareable:
+			return d.decodeShareable(major, minor, v)
+		case tagSharedRef:
+			return d.decodeSharedRef(major, minor, v)
+		}
+		return d.decodeTagged(tag, major, minor, v)
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decodeValue(major, minor, v.Elem())
+	}
+	return d.decodeTagged(noTag, major, minor, v)
+}
+
+// isRawMessagePtr reports whether t is a pointer to a RawMessage
+func isRawMessagePtr(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == rawMessageType
+}
+
+// decodeUntagged decodes an item that isn't tagged into v using the item's
+// major type
+func (d *Decoder) decodeUntagged(major, minor byte, v reflect.Value) error {
+	if v.Kind() == reflect.Interface {
+		if v.NumMethod() != 0 {
+			return d.typeError(major, minor, v)
+		}
+		return d.decodeInterface(major, minor, v)
+	}
+
+	switch major {
+	case majorPositiveInteger, majorNegativeInteger:
+		return d.decodeInteger(major, minor, v)
+	case majorByteString:
+		return d.decodeByteString(minor, v)
+	case majorUnicodeString:
+		if v.Kind() != reflect.String {
+			return d.typeError(major, minor, v)
+		}
+		s, err := d.readString(major, minor)
+		if err != nil {
+			return err
+		}
+		v.SetString(string(s))
+		return nil
+	case majorArray:
+		return d.decodeArray(minor, v)
+	case majorMap:
+		switch v.Kind() {
+		case reflect.Map:
+			return d.decodeMap(minor, v)
+		case reflect.Struct:
+			return d.decodeStruct(minor, v)
+		}
+		return d.typeError(major, minor, v)
+	default:
+		return d.decodeSimpleValue(minor, v)
+	}
+}
+
+// tagTypes are the Go types used to decode tagged items into an empty
+// interface
+var tagTypes = map[uint64]reflect.Type{
+	tagDateTime:       timeType,
+	tagEpoch:          timeType,
+	tagExtendedTime:   timeType,
+	tagDays:           dateType,
+	tagFullDate:       dateType,
+	tagDuration:       durationType,
+	tagComplex:        reflect.TypeOf(complex128(0)),
+	tagPositiveBignum: reflect.PtrTo(bigIntType),
+	tagNegativeBignum: reflect.PtrTo(bigIntType),
+	// decimal fractions and bigfloats
+	tagDecimalFraction: reflect.PtrTo(decimalType),
+	tagBigFloat:        reflect.PtrTo(bigFloatType),
+	// embedded CBOR data item
+	tagEmbedded: reflect.TypeOf(Embedded[interface{}]{}),
+	// multi-dimensional arrays
+	tagMultiDimArray:            reflect.TypeOf(Matrix[interface{}]{}),
+	tagMultiDimArrayColumnMajor: reflect.TypeOf(Matrix[interface{}]{}),
+	// sets
+	tagSet: reflect.TypeOf(Set[interface{}]{}),
+	// standard tags for text strings and UUIDs
+	tagURI:       reflect.PtrTo(urlType),
+	tagBase64URL: base64URLType,
+	tagBase64:    base64Type,
+	tagRegexp:    reflect.PtrTo(regexpType),
+	tagMIME:      reflect.PtrTo(mailMessageType),
+	tagUUID:      uuidType,
+}
+
+// decodeTagged decodes the content of a tag into v, the content's header has
+// been read already. tag is noTag if the item isn't tagged. Tags that aren't
+// known are ignored, and their content is decoded instead.
+func (d *Decoder) decodeTagged(tag uint64, major, minor byte, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decodeTagged(tag, major, minor, v.Elem())
+	case reflect.Interface:
+		// addresses and prefixes are decoded into different types
+		if (tag == tagIPv4 || tag == tagIPv6) && v.NumMethod() == 0 {
+			return d.decodeNetworkAddress(tag, major, minor, v)
+		}
+		if t, ok := tagTypes[tag]; ok && v.NumMethod() == 0 {
+			var x = reflect.New(t).Elem()
+			if err := d.decodeTagged(tag, major, minor, x); err != nil {
+				return err
+			}
+			v.Set(x)
+			return nil
+		}
+	}
+	// types with their own decoding
+	if isEmbedded(v.Type()) {
+		return d.decodeEmbedded(tag, major, minor, v)
+	}
+	switch v.Type() {
+	case timeType:
+		return d.decodeTime(tag, major, minor, v)
+	case dateType:
+		return d.decodeDate(tag, major, minor, v)
+	case durationType:
+		return d.decodeDuration(tag, major, minor, v)
+	case simpleValueType:
+		return d.decodeSimpleValueType(major, minor, v)
+	case bigIntType:
+		return d.decodeBigInt(tag, major, minor, v)
+	case decimalType:
+		var x = v.Addr().Interface().(*Decimal)
+		return d.decodeFraction(tag, tagDecimalFraction, major, minor, v, &x.Exponent, &x.Mantissa)
+	case bigFloatType:
+		var x = v.Addr().Interface().(*BigFloat)
+		return d.decodeFraction(tag, tagBigFloat, major, minor, v, &x.Exponent, &x.Mantissa)
+	case urlType, regexpType, mailMessageType, uuidType, base64URLType, base64Type:
+		return d.decodeStandardTag(tag, major, minor, v)
+	case netipAddrType, netipPrefixType, netIPType, netIPNetType:
+		return d.decodeNetworkAddress(tag, major, minor, v)
+	}
+	if tag == tagMultiDimArray || tag == tagMultiDimArrayColumnMajor {
+		if dims, _ := arrayShape(v.Type()); isMatrix(v.Type()) || len(dims) > 1 {
+			return d.decodeMatrix(tag, major, minor, v)
+		}
+	}
+	if a, ok := parseTypedArrayTag(tag); ok {
+		if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) &&
+			((isNumberKind(v.Type().Elem().Kind()) && v.Type().Elem() != simpleValueType) ||
+				v.Type().Elem() == interfaceType) {
+			return d.decodeTypedArray(a, major, minor, v)
+		}
+	}
+	if v.Kind() == reflect.Complex64 || v.Kind() == reflect.Complex128 {
+		return d.decodeComplex(tag, major, minor, v)
+	}
+	// arrays can be decoded into sets
+	if (tag == tagSet || (tag == noTag && major == majorArray)) && isSetShaped(v.Type()) {
+		return d.decodeSet(major, minor, v)
+	}
+	switch tag {
+	case noTag:
+		return d.decodeUntagged(major, minor, v)
+	case tagPositiveBignum, tagNegativeBignum:
+		return d.decodeBignumInteger(tag, major, minor, v)
+	}
+	return d.decodeValue(major, minor, v)
+}
+
+// decodeInterface stores the item in the empty interface v using the default
+// Go type for the item
+func (d *Decoder) decodeInterface(major, minor byte, v reflect.Value) error {
+	var t reflect.Type
+	switch major {
+	case majorPositiveInteger:
+		t = reflect.TypeOf(uint64(0))
+	case majorNegativeInteger:
+		// integers that don't fit in an int64 are decoded as *big.Int
+		n, err := d.readArgument(minor)
+		if err != nil {
+			return err
+		}
+		if n > math.MaxInt64 {
+			v.Set(reflect.ValueOf(negativeBigInt(n)))
+		} else {
+			v.Set(reflect.ValueOf(-1 - int64(n)))
+		}
+		return nil
+	case majorByteString:
+		t = reflect.TypeOf([]byte(nil))
+	case majorUnicodeString:
+		t = reflect.TypeOf("")
+	case majorArray:
+		t = interfaceSliceType
+	case majorMap:
+		t = interfaceMapType
+	default:
+		switch minor {
+		case simpleValueFalse, simpleValueTrue:
+			t = reflect.TypeOf(false)
+		case minorFloat16, minorFloat32, minorFloat64:
+			t = reflect.TypeOf(float64(0))
+		default:
+			// unassigned simple values
+			if minor > minorInt8 {
+				return d.typeError(major, minor, v)
+			}
+			t = simpleValueType
+		}
+	}
+	var x = reflect.New(t).Elem()
+	if err := d.decodeValue(major, minor, x); err != nil {
+		return err
+	}
+	v.Set(x)
+	return nil
+}
+
+func (d *Decoder) decodeInteger(major, minor byte, v reflect.Value) error {
+	n, err := d.readArgument(minor)
+	if err != nil {
+		return err
+	}
+	var negative = major == majorNegativeInteger
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n > math.MaxInt64 {
+			return overflowError(negative, n, v)
+		}
+		var i = int64(n)
+		if negative {
+			i = -1 - i
+		}
+		if v.OverflowInt(i) {
+			return overflowError(negative, n, v)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if negative {
+			return &UnmarshalTypeError{Value: majorNames[major], Type: v.Type()}
+		}
+		if v.OverflowUint(n) {
+			return overflowError(negative, n, v)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		var f = float64(n)
+		if negative {
+			f = -1 - f
+		}
+		v.SetFloat(f)
+	default:
+		return &UnmarshalTypeError{Value: majorNames[major], Type: v.Type()}
+	}
+	return nil
+}
+
+func overflowError(negative bool, n uint64, v reflect.Value) error {
+	var s = fmt.Sprint(n)
+	if negative {
+		s = "-1-" + s
+	}
+	return fmt.Errorf("cbor: integer %s overflows Go value of type %s", s, v.Type())
+}
+
+func (d *Decoder) decodeByteString(minor byte, v reflect.Value) error {
+	switch {
+	case v.Kind() == reflect.Slice && isByte(v.Type().Elem()):
+		b, err := d.readString(majorByteString, minor)
+		if err != nil {
+			return err
+		}
+		v.SetBytes(b)
+		return nil
+	case v.Kind() == reflect.Array && isByte(v.Type().Elem()):
+		b, err := d.readString(majorByteString, minor)
+		if err != nil {
+			return err
+		}
+		reflect.Copy(v, reflect.ValueOf(b))
+		// zero the rest of the array
+		for i := len(b); i < v.Len(); i++ {
+			v.Index(i).SetUint(0)
+		}
+		return nil
+	}
+	return d.typeError(majorByteString, minor, v)
+}
+
+func (d *Decoder) decodeArray(minor byte, v reflect.Value) error {
+	var i = 0
+	switch v.Kind() {
+	case reflect.Slice:
+		var s = reflect.MakeSlice(v.Type(), 0, 0)
+		err := d.readItems(minor, func(major, minor byte) error {
+			s = reflect.Append(s, reflect.Zero(v.Type().Elem()))
+			i++
+			return d.decodeValue(major, minor, s.Index(i-1))
+		})
+		if err != nil {
+			return err
+		}
+		v.Set(s)
+		return nil
+	case reflect.Array:
+		err := d.readItems(minor, func(major, minor byte) error {
+			i++
+			// drop the items that don't fit in the array
+			if i > v.Len() {
+				return d.skipValue(major, minor)
+			}
+			return d.decodeValue(major, minor, v.Index(i-1))
+		})
+		if err != nil {
+			return err
+		}
+		for ; i < v.Len(); i++ {
+			v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+		}
+		return nil
+	}
+	return d.typeError(majorArray, minor, v)
+}
+
+func (d *Decoder) decodeMap(minor byte, v reflect.Value) error {
+	var t = v.Type()
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(t))
+	}
+	var seen map[interface{}]struct{}
+	return d.readItems(minor, func(major, minor byte) error {
+		var key = reflect.New(t.Key()).Elem()
+		encoded, err := d.decodeKey(major, minor, key)
+		if err != nil {
+			return err
+		}
+		if err := makeHashable(key); err != nil {
+			return err
+		}
+		duplicate, err := d.checkKey(&seen, encoded, key.Interface(), key.Interface())
+		if err != nil {
+			return err
+		}
+		if duplicate {
+			return d.skip()
+		}
+		var value = reflect.New(t.Elem()).Elem()
+		if err := d.decode(value); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, value)
+		return nil
+	})
+}
+
+// makeHashable prepares v to be used as a map key: slices decoded into
+// interfaces in v are replaced by arrays with the same elements. An error is
+// returned if v contains maps or other values that can't be hashed.
+func makeHashable(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		var elem = v.Elem()
+		switch elem.Kind() {
+		case reflect.Slice:
+			var a = reflect.New(reflect.ArrayOf(elem.Len(), elem.Type().Elem())).Elem()
+			reflect.Copy(a, elem)
+			elem = a
+		case reflect.Interface, reflect.Struct, reflect.Array:
+			// copy elem to modify it
+			var c = reflect.New(elem.Type()).Elem()
+			c.Set(elem)
+			elem = c
+		default:
+			if !elem.Type().Comparable() {
+				return fmt.Errorf("cbor: unhashable map key of type %s", elem.Type())
+			}
+			return nil
+		}
+		if err := makeHashable(elem); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).CanSet() {
+				if err := makeHashable(v.Field(i)); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := makeHashable(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		if !v.Type().Comparable() {
+			return fmt.Errorf("cbor: unhashable map key of type %s", v.Type())
+		}
+	}
+	return nil
+}
+
+// decodeStruct decodes a map into the struct v. Entries are matched with the
+// struct's fields using their name, entries that don't match any field are
+// stored in the rest field if there's one, or dropped. An error is returned
+// for keys that can't be stored in the rest field.
+func (d *Decoder) decodeStruct(minor byte, v reflect.Value) error {
+	var fields, rest = structFields(v.Type())
+	var seen map[interface{}]struct{}
+	return d.readItems(minor, func(major, minor byte) error {
+		var key interface{}
+		encoded, err := d.decodeKey(major, minor, reflect.ValueOf(&key).Elem())
+		if err != nil {
+			return err
+		}
+		if name, ok := key.(string); ok {
+			for _, f := range fields {
+				if f.name == name && v.Field(f.index).CanSet() {
+					duplicate, err := d.checkKey(&seen, encoded, fieldIndex(f.index), key)
+					if err != nil {
+						return err
+					}
+					if duplicate {
+						return d.skip()
+					}
+					return d.decode(v.Field(f.index))
+				}
+			}
+		}
+		// keys that can't be hashed are only compared by their encoded form
+		var hashErr = makeHashable(reflect.ValueOf(&key).Elem())
+		var hashed interface{}
+		if hashErr == nil {
+			hashed = key
+		}
+		duplicate, err := d.checkKey(&seen, encoded, hashed, key)
+		if err != nil {
+			return err
+		}
+		if duplicate {
+			return d.skip()
+		}
+		if rest == -1 || !v.Field(rest).CanSet() {
+			return d.skip()
+		}
+		var m = v.Field(rest)
+		if hashErr != nil {
+			return hashErr
+		}
+		var k = reflect.ValueOf(key)
+		if !k.IsValid() {
+			k = reflect.Zero(m.Type().Key())
+		}
+		if !k.Type().AssignableTo(m.Type().Key()) {
+			return &UnmarshalTypeError{Value: "map key of type " + k.Type().String(), Type: m.Type().Key()}
+		}
+		var value = reflect.New(m.Type().Elem()).Elem()
+		if err := d.decode(value); err != nil {
+			return err
+		}
+		if m.IsNil() {
+			m.Set(reflect.MakeMap(m.Type()))
+		}
+		m.SetMapIndex(k, value)
+		return nil
+	})
+}
+
+func (d *Decoder) decodeSimpleValue(minor byte, v reflect.Value) error {
+	n, err := d.readSimpleValue(minor)
+	if err != nil {
+		return err
+	}
+	switch minor {
+	case simpleValueFalse, simpleValueTrue:
+		if v.Kind() != reflect.Bool {
+			break
+		}
+		v.SetBool(minor == simpleValueTrue)
+		return nil
+	case minorFloat16, minorFloat32, minorFloat64:
+		if v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 {
+			break
+		}
+		var f float64
+		switch minor {
+		case minorFloat16:
+			f = float16ToFloat64(uint16(n))
+		case minorFloat32:
+			f = float64(math.Float32frombits(uint32(n)))
+		default:
+			f = math.Float64frombits(n)
+		}
+		v.SetFloat(f)
+		return nil
+	}
+	return &UnmarshalTypeError{Value: majorNames[majorSimpleValue], Type: v.Type()}
+}
@@ -0,0 +1,409 @@
+package cbor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testDecoder decodes input into a new value of the same type as expected,
+// and verifies that it matches expected
+func testDecoder(t *testing.T, input []byte, expected interface{}) {
+	var v = reflect.New(reflect.TypeOf(expected))
+	if err := NewDecoder(bytes.NewReader(input)).Decode(v.Interface()); err != nil {
+		t.Fatalf("err: %#v != nil with %#v", err, input)
+	}
+	if !reflect.DeepEqual(v.Elem().Interface(), expected) {
+		t.Fatalf("(%#v) %#v != %#v", input, v.Elem().Interface(), expected)
+	}
+}
+
+// testDecoderError decodes input into v and verifies it fails
+func testDecoderError(t *testing.T, input []byte, v interface{}) {
+	if err := NewDecoder(bytes.NewReader(input)).Decode(v); err == nil {
+		t.Fatalf("err == nil with %#v", input)
+	}
+}
+
+func TestDecodeInteger(t *testing.T) {
+	var cases = []struct {
+		Value interface{}
+		Input []byte
+	}{
+		{Value: uint64(0), Input: []byte{0x00}},
+		{Value: uint64(23), Input: []byte{0x17}},
+		{Value: uint64(24), Input: []byte{0x18, 0x18}},
+		{Value: uint64(1000), Input: []byte{0x19, 0x03, 0xe8}},
+		{Value: uint32(1000000), Input: []byte{0x1a, 0x00, 0x0f, 0x42, 0x40}},
+		{
+			Value: uint64(18446744073709551615),
+			Input: []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+		{Value: int(-1), Input: []byte{0x20}},
+		{Value: int8(-100), Input: []byte{0x38, 0x63}},
+		{Value: int16(-1000), Input: []byte{0x39, 0x03, 0xe7}},
+		{
+			Value: int64(math.MinInt64),
+			Input: []byte{0x3b, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+		{Value: float64(-10), Input: []byte{0x29}},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%v", c.Value), func(t *testing.T) {
+			testDecoder(t, c.Input, c.Value)
+		})
+	}
+
+	t.Run("overflow", func(t *testing.T) {
+		var i8 int8
+		testDecoderError(t, []byte{0x18, 0x80}, &i8)
+		var u uint
+		testDecoderError(t, []byte{0x20}, &u)
+		var i64 int64
+		testDecoderError(t, []byte{0x3b, 0x80, 0, 0, 0, 0, 0, 0, 0}, &i64)
+	})
+}
+
+func TestDecodeString(t *testing.T) {
+	testDecoder(t, []byte{0x40}, []byte{})
+	testDecoder(t, []byte{0x44, 0x01, 0x02, 0x03, 0x04}, []byte{1, 2, 3, 4})
+	testDecoder(t, []byte{0x42, 0x01, 0x02}, [2]byte{1, 2})
+	testDecoder(t, []byte{0x60}, "")
+	testDecoder(t, []byte{0x64, 0x49, 0x45, 0x54, 0x46}, "IETF")
+	testDecoder(t, []byte{0x63, 0xe6, 0xb0, 0xb4}, "水")
+	// indefinite length strings
+	testDecoder(t,
+		[]byte{0x5f, 0x42, 0x01, 0x02, 0x43, 0x03, 0x04, 0x05, 0xff},
+		[]byte{1, 2, 3, 4, 5},
+	)
+	testDecoder(t,
+		[]byte{0x7f, 0x65, 0x73, 0x74, 0x72, 0x65, 0x61, 0x64, 0x6d, 0x69, 0x6e, 0x67, 0xff},
+		"streaming",
+	)
+	// chunks of an indefinite string must have the same major type
+	var s string
+	testDecoderError(t, []byte{0x7f, 0x41, 0x61, 0xff}, &s)
+}
+
+func TestDecodeFloat(t *testing.T) {
+	var cases = []struct {
+		Value float64
+		Input []byte
+	}{
+		{Value: 0.0, Input: []byte{0xf9, 0x00, 0x00}},
+		{Value: math.Copysign(0, -1), Input: []byte{0xf9, 0x80, 0x00}},
+		{Value: 1.0, Input: []byte{0xf9, 0x3c, 0x00}},
+		{Value: 1.1, Input: []byte{0xfb, 0x3f, 0xf1, 0x99, 0x99, 0x99, 0x99, 0x99, 0x9a}},
+		{Value: 1.5, Input: []byte{0xf9, 0x3e, 0x00}},
+		{Value: 65504, Input: []byte{0xf9, 0x7b, 0xff}},
+		{Value: 100000.0, Input: []byte{0xfa, 0x47, 0xc3, 0x50, 0x00}},
+		{Value: 5.960464477539063e-8, Input: []byte{0xf9, 0x00, 0x01}},
+		{Value: 0.00006103515625, Input: []byte{0xf9, 0x04, 0x00}},
+		{Value: -4.0, Input: []byte{0xf9, 0xc4, 0x00}},
+		{Value: math.Inf(1), Input: []byte{0xf9, 0x7c, 0x00}},
+		{Value: math.Inf(-1), Input: []byte{0xf9, 0xfc, 0x00}},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%v", c.Value), func(t *testing.T) {
+			testDecoder(t, c.Input, c.Value)
+		})
+	}
+
+	t.Run("NaN", func(t *testing.T) {
+		var f float64
+		if err := NewDecoder(bytes.NewReader([]byte{0xf9, 0x7e, 0x00})).Decode(&f); err != nil {
+			t.Fatal(err)
+		}
+		if !math.IsNaN(f) {
+			t.Fatalf("%v isn't NaN", f)
+		}
+	})
+}
+
+func TestDecodeArray(t *testing.T) {
+	testDecoder(t, []byte{0x80}, []int{})
+	testDecoder(t, []byte{0x83, 0x01, 0x02, 0x03}, []int{1, 2, 3})
+	testDecoder(t, []byte{0x83, 0x01, 0x02, 0x03}, [2]int{1, 2})
+	testDecoder(t, []byte{0x81, 0x01}, [2]int{1, 0})
+	testDecoder(t,
+		[]byte{0x83, 0x01, 0x82, 0x02, 0x03, 0x9f, 0x04, 0x05, 0xff},
+		[]interface{}{
+			uint64(1),
+			[]interface{}{uint64(2), uint64(3)},
+			[]interface{}{uint64(4), uint64(5)},
+		},
+	)
+}
+
+func TestDecodeMap(t *testing.T) {
+	testDecoder(t, []byte{0xa0}, map[string]int{})
+	testDecoder(t, []byte{0xa2, 0x01, 0x02, 0x03, 0x04}, map[int]int{1: 2, 3: 4})
+	testDecoder(t,
+		[]byte{0xbf, 0x61, 0x61, 0x01, 0x61, 0x62, 0x9f, 0x02, 0x03, 0xff, 0xff},
+		map[interface{}]interface{}{
+			"a": uint64(1),
+			"b": []interface{}{uint64(2), uint64(3)},
+		},
+	)
+	// arrays are decoded as Go arrays in keys, maps can't be keys
+	testDecoder(t, []byte{0xa1, 0x80, 0x01}, map[interface{}]interface{}{[0]interface{}{}: uint64(1)})
+	var m interface{}
+	testDecoderError(t, []byte{0xa1, 0xa0, 0x01}, &m)
+}
+
+func TestDecodeCompositeMapKeys(t *testing.T) {
+	type Point struct {
+		X int         `cbor:"x"`
+		Y interface{} `cbor:"y"`
+	}
+	var cases = []struct {
+		Value   interface{}
+		Encoded []byte
+	}{
+		{
+			map[Point]string{{X: 1, Y: uint64(2)}: "a"},
+			[]byte{0xa1, 0xa2, 0x61, 0x78, 0x01, 0x61, 0x79, 0x02, 0x61, 0x61},
+		},
+		{
+			map[[2]int]bool{{1, 2}: true},
+			[]byte{0xa1, 0x82, 0x01, 0x02, 0xf5},
+		},
+		{
+			map[interface{}]int{[2]interface{}{"a", [1]interface{}{uint64(1)}}: 1},
+			[]byte{0xa1, 0x82, 0x61, 0x61, 0x81, 0x01, 0x01},
+		},
+		{
+			map[interface{}]int{[2]byte{1, 2}: 1},
+			[]byte{0xa1, 0x42, 0x01, 0x02, 0x01},
+		},
+		{
+			// slices in interface fields are decoded as arrays
+			map[Point]int{{X: 1, Y: [1]interface{}{uint64(2)}}: 3},
+			[]byte{0xa1, 0xa2, 0x61, 0x78, 0x01, 0x61, 0x79, 0x81, 0x02, 0x03},
+		},
+	}
+	for _, c := range cases {
+		testEncoder(t, c.Value, c.Encoded)
+		testDecoder(t, c.Encoded, c.Value)
+	}
+
+	// maps can't be hashed
+	var p map[Point]int
+	testDecoderError(t, []byte{0xa1, 0xa2, 0x61, 0x78, 0x01, 0x61, 0x79, 0xa0, 0x03}, &p)
+	var i map[interface{}]int
+	testDecoderError(t, []byte{0xa1, 0x81, 0xa0, 0x01}, &i)
+}
+
+func TestDecodeSimpleValue(t *testing.T) {
+	testDecoder(t, []byte{0xf4}, false)
+	testDecoder(t, []byte{0xf5}, true)
+	testDecoder(t, []byte{0xf6}, []int(nil))
+	testDecoder(t, []byte{0xf7}, (*int)(nil))
+
+	var i = 12
+	var p = &i
+	if err := NewDecoder(bytes.NewReader([]byte{0xf6})).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	if p != nil {
+		t.Fatalf("%#v != nil", p)
+	}
+}
+
+func TestDecodeStruct(t *testing.T) {
+	type S struct {
+		AField int   `cbor:"a"`
+		BField []int `cbor:"b"`
+		Ignore int   `cbor:"-"`
+		C      string
+	}
+	testDecoder(t,
+		[]byte{
+			0xa4, 0x61, 0x61, 0x01, 0x61, 0x62, 0x82, 0x02, 0x03,
+			0x61, 0x43, 0x61, 0x63, 0x66, 0x49, 0x67, 0x6e, 0x6f, 0x72, 0x65,
+			0x05,
+		},
+		S{AField: 1, BField: []int{2, 3}, C: "c"},
+	)
+	// unknown entries are dropped
+	testDecoder(t,
+		[]byte{0xa2, 0x61, 0x78, 0xa1, 0x01, 0x02, 0x61, 0x61, 0x07},
+		S{AField: 7},
+	)
+	var s S
+	testDecoderError(t, []byte{0xa1, 0x61, 0x61, 0x61, 0x61}, &s)
+}
+
+func TestDecodeRest(t *testing.T) {
+	type S struct {
+		A    int                         `cbor:"a"`
+		Rest map[interface{}]interface{} `cbor:",rest"`
+	}
+	var input = []byte{
+		0xa3, 0x61, 0x61, 0x01, 0x61, 0x62, 0x02, 0x03, 0x82, 0x04, 0x05,
+	}
+	var expected = S{
+		A: 1,
+		Rest: map[interface{}]interface{}{
+			"b":       uint64(2),
+			uint64(3): []interface{}{uint64(4), uint64(5)},
+		},
+	}
+	testDecoder(t, input, expected)
+
+	// the unknown entries are written back when the struct is encoded
+	var buffer bytes.Buffer
+	if err := NewEncoder(&buffer).Encode(expected); err != nil {
+		t.Fatal(err)
+	}
+	testDecoder(t, buffer.Bytes(), expected)
+
+	// keys must fit in the rest field
+	var r struct {
+		Rest map[string]int `cbor:",rest"`
+	}
+	testDecoderError(t, []byte{0xa1, 0x01, 0x02}, &r)
+	testDecoderError(t, []byte{0xa1, 0xa0, 0x02}, &r)
+	var err = NewDecoder(bytes.NewReader([]byte{0xa1, 0x01, 0x02})).Decode(&r)
+	if _, ok := err.(*UnmarshalTypeError); !ok {
+		t.Fatalf("%#v isn't a *UnmarshalTypeError", err)
+	}
+
+	// entries in Rest with the same key as a field are dropped
+	testEncoder(t,
+		S{A: 1, Rest: map[interface{}]interface{}{"a": 2}},
+		[]byte{0xa1, 0x61, 0x61, 0x01},
+	)
+}
+
+func TestDecodeTypeError(t *testing.T) {
+	var s struct {
+		A int
+		B int
+	}
+	var input = []byte{0xa2, 0x61, 0x41, 0x61, 0x78, 0x61, 0x42, 0x01}
+	var d = NewDecoder(bytes.NewReader(append(input, 0x01)))
+	var err = d.Decode(&s)
+	if _, ok := err.(*UnmarshalTypeError); !ok {
+		t.Fatalf("%#v isn't a *UnmarshalTypeError", err)
+	}
+	// the rest of the map isn't read, the decoder can't be used anymore
+	var i interface{}
+	if next := d.Decode(&i); next != err {
+		t.Fatalf("%#v != %#v", next, err)
+	}
+}
+
+func TestDecodeStream(t *testing.T) {
+	var d = NewDecoder(bytes.NewReader([]byte{0x01, 0x82, 0x02, 0x03}))
+	var i int
+	var a []int
+	if err := d.Decode(&i); err != nil || i != 1 {
+		t.Fatalf("%v, %v", i, err)
+	}
+	if err := d.Decode(&a); err != nil || !reflect.DeepEqual(a, []int{2, 3}) {
+		t.Fatalf("%v, %v", a, err)
+	}
+	if err := d.Decode(&i); err != io.EOF {
+		t.Fatalf("%#v != io.EOF", err)
+	}
+	// truncated input
+	d = NewDecoder(bytes.NewReader([]byte{0x82, 0x01}))
+	if err := d.Decode(&a); err != io.ErrUnexpectedEOF {
+		t.Fatalf("%#v != io.ErrUnexpectedEOF", err)
+	}
+	if err := d.Decode(i); err != ErrInvalidDecode {
+		t.Fatalf("%#v != ErrInvalidDecode", err)
+	}
+}
+
+func TestDecodeMaxDepth(t *testing.T) {
+	var nested = func(prefix []byte, n int) []byte {
+		return append(bytes.Repeat(prefix, n), 0x01)
+	}
+	var i interface{}
+	if err := NewDecoder(bytes.NewReader(nested([]byte{0x81}, DefaultMaxDepth))).Decode(&i); err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range [][]byte{
+		nested([]byte{0x81}, DefaultMaxDepth+1),
+		nested([]byte{0xa1, 0x01}, DefaultMaxDepth+1),
+		nested([]byte{0xd9, 0xd9, 0xf7}, DefaultMaxDepth+1),
+		// skipped items
+		append([]byte{0xa1, 0x61, 0x78}, nested([]byte{0x81}, DefaultMaxDepth)...),
+		// a stack overflow without a limit
+		nested([]byte{0x81}, 10000000),
+	} {
+		var s struct{}
+		if err := NewDecoder(bytes.NewReader(input)).Decode(&i); err == nil {
+			t.Fatalf("no error with %d bytes", len(input))
+		}
+		if err := NewDecoder(bytes.NewReader(input)).Decode(&s); err == nil {
+			t.Fatalf("no error with %d bytes", len(input))
+		}
+	}
+
+	var d = NewDecoder(bytes.NewReader([]byte{0x81, 0x81, 0x01}))
+	d.SetMaxDepth(1)
+	if err := d.Decode(&i); err == nil {
+		t.Fatal("no error beyond the maximum depth")
+	}
+}
+
+func TestRawMessage(t *testing.T) {
+	type Envelope struct {
+		Kind string     `cbor:"kind"`
+		Body RawMessage `cbor:"body"`
+	}
+	var input = []byte{
+		0xa2, 0x64, 0x6b, 0x69, 0x6e, 0x64, 0x61, 0x61,
+		0x64, 0x62, 0x6f, 0x64, 0x79, 0x9f, 0x01, 0xa1, 0x61, 0x62, 0x02, 0xff,
+	}
+	var expected = Envelope{
+		Kind: "a",
+		Body: RawMessage{0x9f, 0x01, 0xa1, 0x61, 0x62, 0x02, 0xff},
+	}
+	testDecoder(t, input, expected)
+	// the body is written as is
+	testEncoder(t, expected, input)
+
+	// decode the body later
+	var body []interface{}
+	if err := NewDecoder(bytes.NewReader(expected.Body)).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	// raw messages in raw messages
+	testDecoder(t, []byte{0x82, 0x01, 0x81, 0x02}, []RawMessage{{0x01}, {0x81, 0x02}})
+	testDecoder(t, []byte{0xf6}, RawMessage{0xf6})
+	testEncoder(t, RawMessage(nil), []byte{0xf6})
+
+	var buffer bytes.Buffer
+	for _, invalid := range []RawMessage{{0x82, 0x01}, {0x01, 0x02}, {0xff}} {
+		if err := NewEncoder(&buffer).Encode(invalid); err != ErrInvalidRawMessage {
+			t.Fatalf("%#v != ErrInvalidRawMessage with %#v", err, invalid)
+		}
+	}
+}
+
+func TestDecodeConversion(t *testing.T) {
+	// expected conversion tags are ignored
+	testDecoder(t, []byte{0xd5, 0x42, 0x01, 0x02}, []byte{1, 2})
+	testDecoder(t, []byte{0x81, 0xd7, 0x42, 0x01, 0x02}, []interface{}{[]byte{1, 2}})
+}
+
+func TestDecodeSelfDescribe(t *testing.T) {
+	testDecoder(t, []byte{0xd9, 0xd9, 0xf7, 0x81, 0x02}, []int{2})
+	testDecoder(t, []byte{0xd9, 0xd9, 0xf7, 0x81, 0x02}, []interface{}{uint64(2)})
+	testDecoder(t,
+		[]byte{0xd9, 0xd9, 0xf7, 0xc1, 0x1a, 0x51, 0x4b, 0x67, 0xb0},
+		time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC),
+	)
+	testDecoder(t, []byte{0xd9, 0xd9, 0xf7, 0x01}, RawMessage{0xd9, 0xd9, 0xf7, 0x01})
+}
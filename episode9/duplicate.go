@@ -0,0 +1,84 @@
+package cbor
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// DuplicateKeys selects how the decoder handles maps with the same key more
+// than once. Keys are duplicates if they're equal once decoded or if they have
+// the same encoded form, and struct entries matching the same field are
+// duplicates.
+type DuplicateKeys int
+
+const (
+	// DuplicateKeysLastWins keeps the last value of duplicate keys
+	DuplicateKeysLastWins DuplicateKeys = iota
+	// DuplicateKeysFirstWins keeps the first value of duplicate keys, the
+	// other values are skipped
+	DuplicateKeysFirstWins
+	// DuplicateKeysReject returns a *DuplicateKeyError for duplicate keys
+	DuplicateKeysReject
+)
+
+// DuplicateKeyError is returned by Decode for a map with a duplicate key when
+// duplicate keys are rejected
+type DuplicateKeyError struct {
+	Key interface{} // the decoded key
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("cbor: duplicate map key %v", e.Key)
+}
+
+// SetDuplicateKeys selects how duplicate keys are handled when decoding maps
+// and structs, the default is DuplicateKeysLastWins
+func (d *Decoder) SetDuplicateKeys(mode DuplicateKeys) {
+	d.duplicateKeys = mode
+}
+
+// fieldIndex is the key of a struct field in the keys seen by checkKey
+type fieldIndex int
+
+// encodedKey is the encoded form of a key in the keys seen by checkKey
+type encodedKey string
+
+// decodeKey decodes the key of a map into v, and returns its encoded form when
+// duplicate keys are detected
+func (d *Decoder) decodeKey(major, minor byte, v reflect.Value) (encoded []byte, err error) {
+	if d.duplicateKeys == DuplicateKeysLastWins {
+		return nil, d.decodeValue(major, minor, v)
+	}
+	return d.record(major, minor, func(major, minor byte) error {
+		return d.decodeValue(major, minor, v)
+	})
+}
+
+// checkKey adds the encoded form of a key to seen, and key, its decoded value
+// or the fieldIndex of a struct field, unless it's nil. Decoded keys find
+// duplicates with different encodings, and encoded forms find the keys that
+// can't be hashed or aren't equal to themselves, like NaN. checkKey returns
+// true if the key was already seen, and an error reporting decoded if
+// duplicate keys are rejected.
+func (d *Decoder) checkKey(seen *map[interface{}]struct{}, encoded []byte, key, decoded interface{}) (duplicate bool, err error) {
+	if d.duplicateKeys == DuplicateKeysLastWins {
+		return false, nil
+	}
+	if *seen == nil {
+		*seen = make(map[interface{}]struct{})
+	}
+	for _, k := range []interface{}{key, encodedKey(encoded)} {
+		if k == nil {
+			continue
+		}
+		if _, ok := (*seen)[k]; ok {
+			duplicate = true
+		} else {
+			(*seen)[k] = struct{}{}
+		}
+	}
+	if duplicate && d.duplicateKeys == DuplicateKeysReject {
+		return true, &DuplicateKeyError{decoded}
+	}
+	return duplicate, nil
+}
@@ -0,0 +1,90 @@
+package cbor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestDuplicateKeys(t *testing.T) {
+	type S struct {
+		A int `cbor:"a"`
+	}
+	// {"a": 1, "a": 2} and {1: 1, 1: 2}
+	var structInput = []byte{0xa2, 0x61, 0x61, 0x01, 0x61, 0x61, 0x02}
+	var mapInput = []byte{0xbf, 0x01, 0x01, 0x01, 0x02, 0xff}
+
+	var cases = []struct {
+		Mode   DuplicateKeys
+		Struct S
+		Map    map[int]int
+	}{
+		{DuplicateKeysLastWins, S{A: 2}, map[int]int{1: 2}},
+		{DuplicateKeysFirstWins, S{A: 1}, map[int]int{1: 1}},
+	}
+	for _, c := range cases {
+		var s S
+		var d = NewDecoder(bytes.NewReader(structInput))
+		d.SetDuplicateKeys(c.Mode)
+		if err := d.Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+		if s != c.Struct {
+			t.Fatalf("%#v != %#v", s, c.Struct)
+		}
+
+		var m map[int]int
+		d = NewDecoder(bytes.NewReader(mapInput))
+		d.SetDuplicateKeys(c.Mode)
+		if err := d.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m, c.Map) {
+			t.Fatalf("%#v != %#v", m, c.Map)
+		}
+	}
+
+	for _, c := range []struct {
+		Input []byte
+		Value interface{}
+		Key   interface{}
+	}{
+		{structInput, &S{}, "a"},
+		{mapInput, &map[int]int{}, 1},
+		// keys are compared once decoded, not by their encoded form
+		{[]byte{0xa2, 0x01, 0x01, 0x18, 0x01, 0x02}, &map[int]int{}, 1},
+		{[]byte{0xa2, 0x61, 0x61, 0x01, 0x7f, 0x61, 0x61, 0xff, 0x02}, &S{}, "a"},
+		{[]byte{0xa2, 0x61, 0x62, 0x01, 0x7f, 0x61, 0x62, 0xff, 0x02}, &S{}, "b"},
+		// keys that aren't equal to themselves or can't be hashed
+		{[]byte{0xa2, 0xf9, 0x7e, 0x00, 0x01, 0xf9, 0x7e, 0x00, 0x02}, &map[float64]int{}, math.NaN()},
+		{[]byte{0xa2, 0xa0, 0x01, 0xa0, 0x02}, &S{}, map[interface{}]interface{}{}},
+		// keys are compared after string references are resolved
+		{
+			[]byte{0xd9, 0x01, 0x00, 0xa2, 0x63, 0x61, 0x62, 0x63, 0x01, 0xd8, 0x19, 0x00, 0x02},
+			new(interface{}),
+			"abc",
+		},
+	} {
+		var d = NewDecoder(bytes.NewReader(c.Input))
+		d.SetDuplicateKeys(DuplicateKeysReject)
+		var err = d.Decode(c.Value)
+		var duplicate *DuplicateKeyError
+		if !errors.As(err, &duplicate) {
+			t.Fatalf("expected DuplicateKeyError, got %#v", err)
+		}
+		if fmt.Sprint(duplicate.Key) != fmt.Sprint(c.Key) {
+			t.Fatalf("%#v != %#v", duplicate.Key, c.Key)
+		}
+	}
+
+	// different keys, and keys of different maps
+	var d = NewDecoder(bytes.NewReader([]byte{0xa2, 0x01, 0xa1, 0x01, 0x02, 0x21, 0xa1, 0x01, 0x03}))
+	d.SetDuplicateKeys(DuplicateKeysReject)
+	var i interface{}
+	if err := d.Decode(&i); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,213 @@
+package cbor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DurationFormat selects how the encoder writes time.Duration values
+type DurationFormat int
+
+const (
+	// DurationNanoseconds writes durations as an integer number of
+	// nanoseconds
+	DurationNanoseconds DurationFormat = iota
+	// DurationSeconds writes durations as a number of seconds with the tag
+	// 1002, durations with a fractional second are written as floats
+	DurationSeconds
+	// DurationISO8601 writes durations as ISO 8601 text strings like
+	// PT1H2M3.5S
+	DurationISO8601
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+var errISO8601Duration = errors.New("cbor: invalid ISO 8601 duration")
+
+// SetDurationFormat selects how time.Duration values are encoded, the default
+// is DurationNanoseconds
+func (e *Encoder) SetDurationFormat(f DurationFormat) {
+	e.durationFormat = f
+}
+
+func (e *Encoder) writeDuration(d time.Duration) error {
+	if e.durationFormat == DurationISO8601 {
+		return e.writeUnicodeString(formatISO8601Duration(d))
+	}
+	if err := e.writeTag(tagDuration); err != nil {
+		return err
+	}
+	if d%time.Second == 0 {
+		return e.encode(reflect.ValueOf(int64(d / time.Second)))
+	}
+	return e.writeFloat(d.Seconds())
+}
+
+// formatISO8601Duration formats d with hours, minutes, and seconds, negative
+// durations start with a minus sign
+func formatISO8601Duration(d time.Duration) string {
+	if d == 0 {
+		return "PT0S"
+	}
+	var b strings.Builder
+	var u = uint64(d)
+	if d < 0 {
+		b.WriteByte('-')
+		u = -u
+	}
+	b.WriteString("PT")
+	var (
+		hours   = u / uint64(time.Hour)
+		minutes = u % uint64(time.Hour) / uint64(time.Minute)
+		seconds = u % uint64(time.Minute) / uint64(time.Second)
+		nanos   = u % uint64(time.Second)
+	)
+	if hours > 0 {
+		fmt.Fprintf(&b, "%dH", hours)
+	}
+	if minutes > 0 {
+		fmt.Fprintf(&b, "%dM", minutes)
+	}
+	if seconds > 0 || nanos > 0 {
+		fmt.Fprintf(&b, "%d", seconds)
+		if nanos > 0 {
+			fmt.Fprintf(&b, ".%s", strings.TrimRight(fmt.Sprintf("%09d", nanos), "0"))
+		}
+		b.WriteByte('S')
+	}
+	return b.String()
+}
+
+// parseISO8601Duration parses an ISO 8601 duration with weeks, days, hours,
+// minutes, and seconds. Years and months don't have a fixed duration, they
+// aren't supported.
+func parseISO8601Duration(s string) (time.Duration, error) {
+	var negative bool
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+	if len(s) < 2 || s[0] != 'P' {
+		return 0, errISO8601Duration
+	}
+	s = s[1:]
+
+	var total, limit = new(big.Rat), new(big.Rat).SetInt64(math.MaxInt64)
+	var inTime, last bool
+	for s != "" {
+		if s[0] == 'T' && !inTime {
+			inTime, last = true, false
+			s = s[1:]
+			continue
+		}
+		var i = strings.IndexFunc(s, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.' && r != ','
+		})
+		if i <= 0 {
+			return 0, errISO8601Duration
+		}
+		var n, ok = new(big.Rat).SetString(strings.Replace(s[:i], ",", ".", 1))
+		if !ok {
+			return 0, errISO8601Duration
+		}
+		var unit time.Duration
+		switch {
+		case !inTime && s[i] == 'W':
+			unit = 7 * 24 * time.Hour
+		case !inTime && s[i] == 'D':
+			unit = 24 * time.Hour
+		case inTime && s[i] == 'H':
+			unit = time.Hour
+		case inTime && s[i] == 'M':
+			unit = time.Minute
+		case inTime && s[i] == 'S':
+			unit = time.Second
+		default:
+			return 0, errISO8601Duration
+		}
+		total.Add(total, n.Mul(n, new(big.Rat).SetInt64(int64(unit))))
+		if total.Cmp(limit) > 0 {
+			return 0, fmt.Errorf("cbor: ISO 8601 duration %s overflows time.Duration", s)
+		}
+		s = s[i+1:]
+		last = true
+	}
+	// T must be followed by a time
+	if !last {
+		return 0, errISO8601Duration
+	}
+	// round to the nearest nanosecond
+	var q, r = new(big.Int).QuoRem(total.Num(), total.Denom(), new(big.Int))
+	if r.Lsh(r, 1).Cmp(total.Denom()) >= 0 {
+		q.Add(q, bigOne)
+	}
+	var d = time.Duration(q.Int64())
+	if negative {
+		d = -d
+	}
+	return d, nil
+}
+
+// decodeDuration decodes a number of nanoseconds, a number of seconds with
+// the tag 1002, or an ISO 8601 string into the time.Duration v. Extended
+// durations, a map like extended times, are decoded too.
+func (d *Decoder) decodeDuration(tag uint64, major, minor byte, v reflect.Value) error {
+	switch {
+	case tag == noTag && major != majorUnicodeString:
+		return d.decodeUntagged(major, minor, v)
+	case tag == noTag:
+		s, err := d.readString(major, minor)
+		if err != nil {
+			return err
+		}
+		duration, err := parseISO8601Duration(string(s))
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(duration))
+		return nil
+	case tag != tagDuration:
+		return d.typeError(major, minor, v)
+	}
+
+	var seconds float64
+	switch major {
+	case majorPositiveInteger, majorNegativeInteger:
+		var n int64
+		if err := d.decodeInteger(major, minor, reflect.ValueOf(&n).Elem()); err != nil {
+			return err
+		}
+		if n > math.MaxInt64/int64(time.Second) || n < math.MinInt64/int64(time.Second) {
+			return fmt.Errorf("cbor: %d seconds overflows Go value of type %s", n, v.Type())
+		}
+		v.SetInt(n * int64(time.Second))
+		return nil
+	case majorSimpleValue:
+		if err := d.decodeSimpleValue(minor, reflect.ValueOf(&seconds).Elem()); err != nil {
+			return err
+		}
+	case majorMap:
+		t, err := d.readExtendedTime(minor)
+		if err != nil {
+			return err
+		}
+		seconds = float64(t.Unix())
+		if math.Abs(seconds) < math.MaxInt64/float64(time.Second) {
+			v.SetInt(t.Unix()*int64(time.Second) + int64(t.Nanosecond()))
+			return nil
+		}
+	default:
+		return d.typeError(major, minor, v)
+	}
+	var nanoseconds = math.Round(seconds * float64(time.Second))
+	if math.IsNaN(nanoseconds) || math.Abs(nanoseconds) >= math.MaxInt64 {
+		return fmt.Errorf("cbor: %g seconds overflows Go value of type %s", seconds, v.Type())
+	}
+	v.SetInt(int64(nanoseconds))
+	return nil
+}
@@ -0,0 +1,97 @@
+package cbor
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestDuration(t *testing.T) {
+	var d = 1500 * time.Millisecond
+	var nanoseconds = []byte{0x1a, 0x59, 0x68, 0x2f, 0x00}
+	testEncoder(t, d, nanoseconds)
+
+	var cases = []struct {
+		Format   DurationFormat
+		Duration time.Duration
+		Encoded  []byte
+	}{
+		{DurationSeconds, 3 * time.Second, []byte{0xd9, 0x03, 0xea, 0x03}},
+		{DurationSeconds, -3 * time.Second, []byte{0xd9, 0x03, 0xea, 0x22}},
+		{DurationSeconds, d, []byte{0xd9, 0x03, 0xea, 0xf9, 0x3e, 0x00}},
+		{
+			DurationISO8601,
+			time.Hour + 2*time.Minute + 3500*time.Millisecond,
+			[]byte{0x6a, 0x50, 0x54, 0x31, 0x48, 0x32, 0x4d, 0x33, 0x2e, 0x35, 0x53},
+		},
+		{DurationISO8601, -time.Second, []byte{0x65, 0x2d, 0x50, 0x54, 0x31, 0x53}},
+		{DurationISO8601, 0, []byte{0x64, 0x50, 0x54, 0x30, 0x53}},
+	}
+	for _, c := range cases {
+		var buffer bytes.Buffer
+		var e = NewEncoder(&buffer)
+		e.SetDurationFormat(c.Format)
+		if err := e.Encode(c.Duration); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buffer.Bytes(), c.Encoded) {
+			t.Fatalf("%#v != %#v", buffer.Bytes(), c.Encoded)
+		}
+		testDecoder(t, c.Encoded, c.Duration)
+	}
+
+	// typed arrays only hold durations in nanoseconds
+	for _, c := range []struct {
+		Format  DurationFormat
+		Encoded []byte
+	}{
+		{DurationNanoseconds, []byte{0xd8, 0x4f, 0x48, 0x00, 0xca, 0x9a, 0x3b, 0x00, 0x00, 0x00, 0x00}},
+		{DurationSeconds, []byte{0x81, 0xd9, 0x03, 0xea, 0x01}},
+	} {
+		var buffer bytes.Buffer
+		var e = NewEncoder(&buffer)
+		e.SetTypedArrays(true)
+		e.SetDurationFormat(c.Format)
+		if err := e.Encode([]time.Duration{time.Second}); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buffer.Bytes(), c.Encoded) {
+			t.Fatalf("%#v != %#v", buffer.Bytes(), c.Encoded)
+		}
+	}
+
+	testDecoder(t, nanoseconds, d)
+	// extended durations
+	testDecoder(t, []byte{0xd9, 0x03, 0xea, 0xa2, 0x01, 0x01, 0x22, 0x19, 0x01, 0xf4}, d)
+	// into interface{}
+	testDecoder(t, []byte{0x81, 0xd9, 0x03, 0xea, 0x03}, []interface{}{3 * time.Second})
+
+	var x time.Duration
+	testDecoderError(t, []byte{0xd9, 0x03, 0xea, 0x61, 0x31}, &x)
+	testDecoderError(t, []byte{0xd9, 0x03, 0xea, 0x1b, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, &x)
+}
+
+func TestParseISO8601Duration(t *testing.T) {
+	var valid = map[string]time.Duration{
+		"P1DT1H":         25 * time.Hour,
+		"P1W":            7 * 24 * time.Hour,
+		"PT0,5S":         500 * time.Millisecond,
+		"PT1.5M":         90 * time.Second,
+		"-PT1M30S":       -90 * time.Second,
+		"PT0.000000001S": 1,
+	}
+	for s, expected := range valid {
+		d, err := parseISO8601Duration(s)
+		if err != nil {
+			t.Fatalf("%s: %s", s, err)
+		}
+		if d != expected {
+			t.Fatalf("%s: %s != %s", s, d, expected)
+		}
+	}
+	for _, s := range []string{"", "P", "PT", "P1DT", "1S", "P1Y", "P1M", "PT1D", "PTS", "P1000000W"} {
+		if _, err := parseISO8601Duration(s); err == nil {
+			t.Fatalf("%s: err == nil", s)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package cbor
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+)
+
+// Embedded is a CBOR data item embedded in a byte string with the tag 24.
+//
+// When decoding the byte string must contain a single well-formed item, it's
+// decoded into Value and kept as is in Raw, to check a signature for example.
+// When encoding Value is encoded, Raw is written instead only if Value is the
+// zero value and Raw isn't empty.
+type Embedded[T any] struct {
+	Value T
+	Raw   RawMessage
+}
+
+func (e *Embedded[T]) embedded() (value reflect.Value, raw *RawMessage) {
+	return reflect.ValueOf(&e.Value).Elem(), &e.Raw
+}
+
+// embeddedItem is implemented by all the Embedded types
+type embeddedItem interface {
+	embedded() (value reflect.Value, raw *RawMessage)
+}
+
+var embeddedItemType = reflect.TypeOf((*embeddedItem)(nil)).Elem()
+
+// isEmbedded reports whether t is an Embedded type
+func isEmbedded(t reflect.Type) bool {
+	return isGenericInstance(t, "Embedded") && reflect.PtrTo(t).Implements(embeddedItemType)
+}
+
+// isGenericInstance reports whether t is an instance of the generic struct
+// type of this package with the given name. Structs embedding it have its
+// methods too, so methods alone can't identify it.
+func isGenericInstance(t reflect.Type, name string) bool {
+	return t.Kind() == reflect.Struct && t.PkgPath() == embeddedItemType.PkgPath() &&
+		strings.HasPrefix(t.Name(), name+"[")
+}
+
+// writeEmbedded writes x, an Embedded value, as a byte string with the tag 24
+func (e *Encoder) writeEmbedded(x reflect.Value) error {
+	var p = reflect.New(x.Type())
+	p.Elem().Set(reflect.ValueOf(valueInterface(x)))
+	var value, raw = p.Interface().(embeddedItem).embedded()
+
+	var b = []byte(*raw)
+	if len(b) == 0 || !value.IsZero() {
+		// encode the value with a copy of the encoder writing in a buffer
+		var buffer bytes.Buffer
+		var inner = *e
+		inner.w = &buffer
+		inner.shared = nil
+		inner.namespace = nil
+		if err := inner.encode(value); err != nil {
+			return err
+		}
+		b = buffer.Bytes()
+	} else if !validRawMessage(b) {
+		return ErrInvalidRawMessage
+	}
+	if err := e.writeTag(tagEmbedded); err != nil {
+		return err
+	}
+	return e.writeByteString(b)
+}
+
+// decodeEmbedded decodes an embedded item into the Embedded value v
+func (d *Decoder) decodeEmbedded(tag uint64, major, minor byte, v reflect.Value) error {
+	if (tag != noTag && tag != tagEmbedded) || major != majorByteString {
+		return d.typeError(major, minor, v)
+	}
+	b, err := d.readString(major, minor)
+	if err != nil {
+		return err
+	}
+	if !validRawMessage(b) {
+		return ErrMalformed
+	}
+	var value, raw = v.Addr().Interface().(embeddedItem).embedded()
+	*raw = RawMessage(b)
+	// decode the value with a copy of the decoder reading the byte string
+	var inner = *d
+	inner.r = bytes.NewReader(b)
+	inner.raw = nil
+	inner.recording = 0
+	inner.shared = nil
+	inner.namespace = nil
+	return inner.decode(value)
+}
@@ -0,0 +1,75 @@
+package cbor
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestEmbedded(t *testing.T) {
+	type Payload struct {
+		A int `cbor:"a"`
+	}
+	type Signed struct {
+		Document  Embedded[Payload] `cbor:"doc"`
+		Signature []byte            `cbor:"sig"`
+	}
+	var encoded = []byte{
+		0xa2,
+		0x63, 0x64, 0x6f, 0x63, 0xd8, 0x18, 0x44, 0xa1, 0x61, 0x61, 0x01,
+		0x63, 0x73, 0x69, 0x67, 0x41, 0xff,
+	}
+	testEncoder(t,
+		Signed{Document: Embedded[Payload]{Value: Payload{A: 1}}, Signature: []byte{0xff}},
+		encoded,
+	)
+
+	var s Signed
+	if err := NewDecoder(bytes.NewReader(encoded)).Decode(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Document.Value.A != 1 {
+		t.Fatalf("%#v != 1", s.Document.Value.A)
+	}
+	if raw := (RawMessage{0xa1, 0x61, 0x61, 0x01}); !bytes.Equal(s.Document.Raw, raw) {
+		t.Fatalf("%#v != %#v", s.Document.Raw, raw)
+	}
+
+	// Value is encoded when it's set, Raw is written as is otherwise
+	s.Document.Value.A = 2
+	testEncoder(t, s.Document, []byte{0xd8, 0x18, 0x44, 0xa1, 0x61, 0x61, 0x02})
+	s.Document.Value.A = 0
+	testEncoder(t, s, encoded)
+
+	// structs embedding an Embedded are regular structs
+	type Envelope struct {
+		Embedded[Payload]
+		Signature []byte `cbor:"sig"`
+	}
+	var envelope = Envelope{Embedded[Payload]{Value: Payload{A: 1}}, []byte{0xff}}
+	var envelopeEncoded = []byte{
+		0xa2,
+		0x68, 0x45, 0x6d, 0x62, 0x65, 0x64, 0x64, 0x65, 0x64,
+		0xd8, 0x18, 0x44, 0xa1, 0x61, 0x61, 0x01,
+		0x63, 0x73, 0x69, 0x67, 0x41, 0xff,
+	}
+	testEncoder(t, envelope, envelopeEncoded)
+	envelope.Raw = RawMessage{0xa1, 0x61, 0x61, 0x01}
+	testDecoder(t, envelopeEncoded, envelope)
+
+	// into interface{}
+	var i interface{}
+	if err := NewDecoder(bytes.NewReader([]byte{0xd8, 0x18, 0x41, 0x01})).Decode(&i); err != nil {
+		t.Fatal(err)
+	}
+	var expected = Embedded[interface{}]{Value: uint64(1), Raw: RawMessage{0x01}}
+	if !reflect.DeepEqual(i, expected) {
+		t.Fatalf("%#v != %#v", i, expected)
+	}
+
+	// the byte string must contain a single well-formed item
+	var e Embedded[int]
+	testDecoderError(t, []byte{0xd8, 0x18, 0x42, 0x01, 0x02}, &e)
+	testDecoderError(t, []byte{0xd8, 0x18, 0x41, 0x81}, &e)
+	testDecoderError(t, []byte{0xd8, 0x18, 0x01}, &e)
+}
@@ -0,0 +1,173 @@
+package cbor
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Matrix is a multi-dimensional array from RFC 8746. Data holds the items in
+// row-major order, or in column-major order if ColumnMajor is true. It's
+// encoded with the tag 40, or 1040 for column-major matrices.
+type Matrix[T any] struct {
+	Dims        []int
+	Data        []T
+	ColumnMajor bool
+}
+
+func (m *Matrix[T]) matrix() (dims *[]int, data reflect.Value, columnMajor *bool) {
+	return &m.Dims, reflect.ValueOf(&m.Data).Elem(), &m.ColumnMajor
+}
+
+// matrix is implemented by all the Matrix types
+type matrix interface {
+	matrix() (dims *[]int, data reflect.Value, columnMajor *bool)
+}
+
+var matrixType = reflect.TypeOf((*matrix)(nil)).Elem()
+
+// isMatrix reports whether t is a Matrix type
+func isMatrix(t reflect.Type) bool {
+	return isGenericInstance(t, "Matrix") && reflect.PtrTo(t).Implements(matrixType)
+}
+
+// arrayShape returns the dimensions of the nested arrays of type t, and the
+// type of their items. Byte arrays are items since they're byte strings.
+func arrayShape(t reflect.Type) (dims []int, elem reflect.Type) {
+	for t.Kind() == reflect.Array && !isByte(t.Elem()) {
+		dims = append(dims, t.Len())
+		t = t.Elem()
+	}
+	return dims, t
+}
+
+// dimsLen returns the number of items in a matrix with the dimensions dims,
+// ok is false if the dimensions are invalid
+func dimsLen(dims []int) (n int, ok bool) {
+	const maxInt = int(^uint(0) >> 1)
+	n = 1
+	for _, d := range dims {
+		if d < 0 || (d > 0 && n > maxInt/d) {
+			return 0, false
+		}
+		n *= d
+	}
+	return n, len(dims) > 0
+}
+
+// writeMatrix writes a multi-dimensional array: an array with the dimensions
+// followed by the items
+func (e *Encoder) writeMatrix(columnMajor bool, dims []int, data reflect.Value) error {
+	if n, ok := dimsLen(dims); !ok || n != data.Len() {
+		return fmt.Errorf("cbor: matrix with dimensions %v can't have %d items", dims, data.Len())
+	}
+	var tag uint64 = tagMultiDimArray
+	if columnMajor {
+		tag = tagMultiDimArrayColumnMajor
+	}
+	if err := e.writeTag(tag); err != nil {
+		return err
+	}
+	if err := e.writeInteger(majorArray, 2); err != nil {
+		return err
+	}
+	// the dimensions are always a regular array
+	if err := e.writeInteger(majorArray, uint64(len(dims))); err != nil {
+		return err
+	}
+	for _, d := range dims {
+		if err := e.writeInteger(majorPositiveInteger, uint64(d)); err != nil {
+			return err
+		}
+	}
+	return e.encode(data)
+}
+
+// writeMatrixValue writes x, a Matrix value
+func (e *Encoder) writeMatrixValue(x reflect.Value) error {
+	var p = reflect.New(x.Type())
+	p.Elem().Set(reflect.ValueOf(valueInterface(x)))
+	var dims, data, columnMajor = p.Interface().(matrix).matrix()
+	return e.writeMatrix(*columnMajor, *dims, data)
+}
+
+// writeArrayMatrix writes the nested arrays x as a row-major matrix
+func (e *Encoder) writeArrayMatrix(x reflect.Value, dims []int, elem reflect.Type) error {
+	var data = reflect.MakeSlice(reflect.SliceOf(elem), 0, x.Len())
+	var flatten func(v reflect.Value, depth int)
+	flatten = func(v reflect.Value, depth int) {
+		if depth == len(dims) {
+			data = reflect.Append(data, exported(v))
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			flatten(v.Index(i), depth+1)
+		}
+	}
+	flatten(x, 0)
+	return e.writeMatrix(false, dims, data)
+}
+
+// readMatrix reads the content of a multi-dimensional array with the given
+// header into dims and data
+func (d *Decoder) readMatrix(major, minor byte, v reflect.Value, dims *[]int, data reflect.Value) error {
+	if major != majorArray {
+		return d.typeError(major, minor, v)
+	}
+	var i = 0
+	err := d.readItems(minor, func(major, minor byte) error {
+		i++
+		switch i {
+		case 1:
+			return d.decodeValue(major, minor, reflect.ValueOf(dims).Elem())
+		case 2:
+			return d.decodeValue(major, minor, data)
+		}
+		return ErrMalformed
+	})
+	if err != nil {
+		return err
+	}
+	if n, ok := dimsLen(*dims); i != 2 || !ok || n != data.Len() {
+		return ErrMalformed
+	}
+	return nil
+}
+
+// decodeMatrix decodes a multi-dimensional array into v, a Matrix value or
+// nested arrays with the same dimensions
+func (d *Decoder) decodeMatrix(tag uint64, major, minor byte, v reflect.Value) error {
+	var columnMajor = tag == tagMultiDimArrayColumnMajor
+	if isMatrix(v.Type()) {
+		var dims, data, c = v.Addr().Interface().(matrix).matrix()
+		*c = columnMajor
+		return d.readMatrix(major, minor, v, dims, data)
+	}
+
+	var shape, elem = arrayShape(v.Type())
+	var dims []int
+	var data = reflect.New(reflect.SliceOf(elem)).Elem()
+	if err := d.readMatrix(major, minor, v, &dims, data); err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(dims, shape) {
+		return fmt.Errorf("cbor: matrix with dimensions %v can't be decoded into Go value of type %s", dims, v.Type())
+	}
+	var index = make([]int, len(dims))
+	for i := 0; i < data.Len(); i++ {
+		// compute the index of the item in each dimension
+		var rest = i
+		for k := range dims {
+			if !columnMajor {
+				k = len(dims) - 1 - k
+			}
+			index[k] = rest % dims[k]
+			rest /= dims[k]
+		}
+		var item = v
+		for _, j := range index {
+			item = item.Index(j)
+		}
+		item.Set(data.Index(i))
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package cbor
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMatrix(t *testing.T) {
+	// example from RFC 8746: a 2×3 matrix of uint16 in a typed array
+	var m = Matrix[uint16]{Dims: []int{2, 3}, Data: []uint16{2, 4, 8, 4, 16, 256}}
+	var encoded = []byte{
+		0xd8, 0x28, 0x82, 0x82, 0x02, 0x03, 0xd8, 0x45, 0x4c,
+		0x02, 0x00, 0x04, 0x00, 0x08, 0x00, 0x04, 0x00, 0x10, 0x00, 0x00, 0x01,
+	}
+	testTypedArrayEncoder(t, m, encoded)
+	testDecoder(t, encoded, m)
+	testDecoder(t, encoded, [2][3]uint16{{2, 4, 8}, {4, 16, 256}})
+
+	// without typed arrays
+	var plain = []byte{
+		0xd9, 0x04, 0x10, 0x82, 0x82, 0x02, 0x01, 0x82, 0x01, 0x02,
+	}
+	var c = Matrix[int]{Dims: []int{2, 1}, Data: []int{1, 2}, ColumnMajor: true}
+	testEncoder(t, c, plain)
+	testDecoder(t, plain, c)
+	testDecoder(t, plain, [2][1]int{{1}, {2}})
+
+	// into interface{}
+	testDecoder(t, append([]byte{0x81}, plain...), []interface{}{
+		Matrix[interface{}]{
+			Dims:        []int{2, 1},
+			Data:        []interface{}{uint64(1), uint64(2)},
+			ColumnMajor: true,
+		},
+	})
+	testDecoder(t, append([]byte{0x81}, encoded...), []interface{}{
+		Matrix[interface{}]{
+			Dims: []int{2, 3},
+			Data: []interface{}{uint16(2), uint16(4), uint16(8), uint16(4), uint16(16), uint16(256)},
+		},
+	})
+
+	// structs embedding a Matrix are regular structs
+	type Image struct {
+		Matrix[int]
+		Name string `cbor:"name"`
+	}
+	var image = Image{Matrix[int]{Dims: []int{2, 1}, Data: []int{1, 2}, ColumnMajor: true}, "a"}
+	var imageEncoded = append(append([]byte{
+		0xa2, 0x66, 0x4d, 0x61, 0x74, 0x72, 0x69, 0x78,
+	}, plain...), 0x64, 0x6e, 0x61, 0x6d, 0x65, 0x61, 0x61)
+	testEncoder(t, image, imageEncoded)
+	testDecoder(t, imageEncoded, image)
+
+	var x Matrix[int]
+	// invalid number of items
+	testDecoderError(t, []byte{0xd8, 0x28, 0x82, 0x81, 0x02, 0x81, 0x01}, &x)
+	if err := NewEncoder(&bytes.Buffer{}).Encode(Matrix[int]{Dims: []int{2}}); err == nil {
+		t.Fatal("err == nil with invalid dimensions")
+	}
+	var a [2][2]int
+	testDecoderError(t, []byte{0xd8, 0x28, 0x82, 0x82, 0x01, 0x02, 0x82, 0x01, 0x02}, &a)
+}
+
+func TestMatrixArray(t *testing.T) {
+	var a = [2][3]float64{{1, 2, 3}, {4, 5, 6}}
+	var buffer bytes.Buffer
+	var e = NewEncoder(&buffer)
+	e.SetMatrices(true)
+	e.SetTypedArrays(true)
+	if err := e.Encode(a); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buffer.Bytes(), []byte{0xd8, 0x28, 0x82, 0x82, 0x02, 0x03, 0xd8, 0x56, 0x58, 0x30}) {
+		t.Fatalf("%#v isn't a matrix", buffer.Bytes())
+	}
+	testDecoder(t, buffer.Bytes(), a)
+	testDecoder(t, buffer.Bytes(), Matrix[float64]{Dims: []int{2, 3}, Data: []float64{1, 2, 3, 4, 5, 6}})
+
+	// nil interfaces keep their type
+	buffer.Reset()
+	if err := e.Encode([2][2]interface{}{{1, nil}, {2, 3}}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []byte{
+		0xd8, 0x28, 0x82, 0x82, 0x02, 0x02, 0x84, 0x01, 0xf6, 0x02, 0x03,
+	}; !bytes.Equal(buffer.Bytes(), expected) {
+		t.Fatalf("%#v != %#v", buffer.Bytes(), expected)
+	}
+
+	// single dimension arrays and byte arrays aren't matrices
+	buffer.Reset()
+	if err := e.Encode([2][2]byte{{1, 2}, {3, 4}}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []byte{0x82, 0x42, 0x01, 0x02, 0x42, 0x03, 0x04}; !bytes.Equal(buffer.Bytes(), expected) {
+		t.Fatalf("%#v != %#v", buffer.Bytes(), expected)
+	}
+	// nested arrays without the option
+	testEncoder(t, [2][1]int{{1}, {2}}, []byte{0x82, 0x81, 0x01, 0x81, 0x02})
+}
@@ -0,0 +1,246 @@
+package cbor
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"reflect"
+	"strconv"
+)
+
+var (
+	netipAddrType   = reflect.TypeOf(netip.Addr{})
+	netipPrefixType = reflect.TypeOf(netip.Prefix{})
+	netIPType       = reflect.TypeOf(net.IP(nil))
+	netIPNetType    = reflect.TypeOf(net.IPNet{})
+)
+
+// addrTag returns the tag of the address a: 52 for IPv4, 54 for IPv6
+func addrTag(a netip.Addr) uint64 {
+	if a.Is4() {
+		return tagIPv4
+	}
+	return tagIPv6
+}
+
+// writeAddr writes the address a with the tag 52 or 54. Addresses with a zone
+// are written as an array of the address, null, and the zone. The zero Addr is
+// written as null.
+func (e *Encoder) writeAddr(a netip.Addr) error {
+	if !a.IsValid() {
+		return e.writeHeader(majorSimpleValue, simpleValueNil)
+	}
+	if err := e.writeTag(addrTag(a)); err != nil {
+		return err
+	}
+	if a.Zone() == "" {
+		return e.writeByteString(a.AsSlice())
+	}
+	if err := e.writeInteger(majorArray, 3); err != nil {
+		return err
+	}
+	if err := e.writeByteString(a.AsSlice()); err != nil {
+		return err
+	}
+	if err := e.writeHeader(majorSimpleValue, simpleValueNil); err != nil {
+		return err
+	}
+	return e.writeUnicodeString(a.Zone())
+}
+
+// writePrefix writes the prefix p with the tag 52 or 54. Prefixes without host
+// bits are written as an array of the prefix length and the address without
+// its trailing zero bytes, other prefixes are written as an array of the
+// address and the prefix length. The zero Prefix is written as null.
+func (e *Encoder) writePrefix(p netip.Prefix) error {
+	if !p.IsValid() {
+		return e.writeHeader(majorSimpleValue, simpleValueNil)
+	}
+	if err := e.writeTag(addrTag(p.Addr())); err != nil {
+		return err
+	}
+	if err := e.writeInteger(majorArray, 2); err != nil {
+		return err
+	}
+	var b = p.Addr().AsSlice()
+	if p.Masked() != p {
+		if err := e.writeByteString(b); err != nil {
+			return err
+		}
+		return e.writeInteger(majorPositiveInteger, uint64(p.Bits()))
+	}
+	for len(b) > 0 && b[len(b)-1] == 0 {
+		b = b[:len(b)-1]
+	}
+	if err := e.writeInteger(majorPositiveInteger, uint64(p.Bits())); err != nil {
+		return err
+	}
+	return e.writeByteString(b)
+}
+
+// writeIP writes ip like a netip.Addr, IPv4 addresses in their 16 bytes form
+// are written as IPv4 addresses. A nil IP is written as null.
+func (e *Encoder) writeIP(ip net.IP) error {
+	if len(ip) == 0 {
+		return e.writeHeader(majorSimpleValue, simpleValueNil)
+	}
+	a, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return &UnsupportedValueError{reflect.ValueOf(ip), fmt.Sprintf("invalid IP address length %d", len(ip))}
+	}
+	if ip.To4() != nil {
+		a = a.Unmap()
+	}
+	return e.writeAddr(a)
+}
+
+// writeIPNet writes n like a netip.Prefix
+func (e *Encoder) writeIPNet(n net.IPNet) error {
+	var ones, bits = n.Mask.Size()
+	a, ok := netip.AddrFromSlice(n.IP)
+	if bits == 32 {
+		a = a.Unmap()
+	}
+	if !ok || bits == 0 || a.BitLen() != bits {
+		return &UnsupportedValueError{reflect.ValueOf(n), "invalid IP network " + n.String()}
+	}
+	return e.writePrefix(netip.PrefixFrom(a, ones))
+}
+
+// readNetworkAddress reads the content of a tag 52 or 54: an address, a
+// prefix, or an address with a prefix length and a zone. bits is -1 if
+// there's no prefix length.
+func (d *Decoder) readNetworkAddress(tag uint64, major, minor byte) (addr netip.Addr, bits int, err error) {
+	var size = 16
+	if tag == tagIPv4 {
+		size = 4
+	}
+	// readAddr converts b to an address, prefixes omit the trailing zero
+	// bytes
+	var readAddr = func(b []byte, prefix bool) (netip.Addr, error) {
+		if prefix && (len(b) > size || (len(b) > 0 && b[len(b)-1] == 0)) {
+			return netip.Addr{}, ErrMalformed
+		}
+		if !prefix && len(b) != size {
+			return netip.Addr{}, ErrMalformed
+		}
+		var full [16]byte
+		copy(full[:], b)
+		if size == 4 {
+			return netip.AddrFrom4([4]byte(full[:4])), nil
+		}
+		return netip.AddrFrom16(full), nil
+	}
+
+	switch major {
+	case majorByteString:
+		b, err := d.readString(major, minor)
+		if err != nil {
+			return addr, 0, err
+		}
+		addr, err = readAddr(b, false)
+		return addr, -1, err
+	case majorArray:
+	default:
+		return addr, 0, ErrMalformed
+	}
+
+	// prefixes start with the prefix length, addresses with a prefix length
+	// or a zone start with the address
+	var n = 0
+	var prefix bool
+	var zone string
+	bits = -1
+	err = d.readItems(minor, func(major, minor byte) error {
+		n++
+		switch {
+		case n == 1 && major == majorPositiveInteger:
+			prefix = true
+			fallthrough
+		case n == 2 && !prefix && major == majorPositiveInteger:
+			length, err := d.readArgument(minor)
+			if err != nil {
+				return err
+			}
+			if length > uint64(size)*8 {
+				return ErrMalformed
+			}
+			bits = int(length)
+		case n == 1 && major == majorByteString, n == 2 && prefix && major == majorByteString:
+			b, err := d.readString(major, minor)
+			if err != nil {
+				return err
+			}
+			addr, err = readAddr(b, prefix)
+			return err
+		case n == 2 && !prefix && major == majorSimpleValue && minor == simpleValueNil:
+		case n == 3 && !prefix && major == majorUnicodeString:
+			s, err := d.readString(major, minor)
+			if err != nil {
+				return err
+			}
+			zone = string(s)
+		case n == 3 && !prefix && major == majorPositiveInteger:
+			i, err := d.readArgument(minor)
+			if err != nil {
+				return err
+			}
+			zone = strconv.FormatUint(i, 10)
+		default:
+			return ErrMalformed
+		}
+		return nil
+	})
+	if err != nil {
+		return addr, 0, err
+	}
+	if n < 2 || (prefix && n != 2) {
+		return addr, 0, ErrMalformed
+	}
+	// prefixes can't have host bits
+	if prefix && netip.PrefixFrom(addr, bits).Masked().Addr() != addr {
+		return addr, 0, ErrMalformed
+	}
+	return addr.WithZone(zone), bits, nil
+}
+
+// decodeNetworkAddress decodes the content of a tag 52 or 54 into v. Untagged
+// byte strings can be decoded into a net.IP.
+func (d *Decoder) decodeNetworkAddress(tag uint64, major, minor byte, v reflect.Value) error {
+	if tag != tagIPv4 && tag != tagIPv6 {
+		if tag == noTag && v.Type() == netIPType {
+			return d.decodeUntagged(major, minor, v)
+		}
+		return d.typeError(major, minor, v)
+	}
+	addr, bits, err := d.readNetworkAddress(tag, major, minor)
+	if err != nil {
+		return err
+	}
+
+	// net.IP and prefixes can't have a zone
+	var zone = addr.Zone() != ""
+	var x interface{}
+	switch {
+	case v.Type() == netipAddrType && bits < 0:
+		x = addr
+	case v.Type() == netIPType && bits < 0 && !zone:
+		x = net.IP(addr.AsSlice())
+	case v.Type() == netipPrefixType && bits >= 0 && !zone:
+		x = netip.PrefixFrom(addr, bits)
+	case v.Type() == netIPNetType && bits >= 0 && !zone:
+		x = net.IPNet{IP: addr.AsSlice(), Mask: net.CIDRMask(bits, addr.BitLen())}
+	case v.Kind() == reflect.Interface && bits < 0:
+		x = addr
+	case v.Kind() == reflect.Interface && !zone:
+		x = netip.PrefixFrom(addr, bits)
+	default:
+		var value = "IP address"
+		if bits >= 0 {
+			value = "IP prefix"
+		}
+		return &UnmarshalTypeError{Value: value, Type: v.Type()}
+	}
+	v.Set(reflect.ValueOf(x))
+	return nil
+}
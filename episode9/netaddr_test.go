@@ -0,0 +1,105 @@
+package cbor
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func TestNetworkAddress(t *testing.T) {
+	var ipv6 = []byte{
+		0x20, 0x01, 0x0d, 0xb8, 0x12, 0x34, 0xde, 0xed,
+		0xbe, 0xef, 0xca, 0xfe, 0xfa, 0xce, 0xfe, 0xed,
+	}
+	var cases = []struct {
+		Value   interface{}
+		Encoded []byte
+	}{
+		// examples from RFC 9164
+		{
+			netip.MustParseAddr("192.0.2.15"),
+			[]byte{0xd8, 0x34, 0x44, 0xc0, 0x00, 0x02, 0x0f},
+		},
+		{
+			netip.MustParsePrefix("192.0.2.0/24"),
+			[]byte{0xd8, 0x34, 0x82, 0x18, 0x18, 0x43, 0xc0, 0x00, 0x02},
+		},
+		{
+			netip.MustParsePrefix("192.0.2.15/24"),
+			[]byte{0xd8, 0x34, 0x82, 0x44, 0xc0, 0x00, 0x02, 0x0f, 0x18, 0x18},
+		},
+		{
+			netip.MustParseAddr("2001:db8:1234:deed:beef:cafe:face:feed"),
+			append([]byte{0xd8, 0x36, 0x50}, ipv6...),
+		},
+		{
+			netip.MustParsePrefix("2001:db8:1234::/48"),
+			[]byte{0xd8, 0x36, 0x82, 0x18, 0x30, 0x46, 0x20, 0x01, 0x0d, 0xb8, 0x12, 0x34},
+		},
+		{
+			netip.MustParsePrefix("2001:db8:1234:deed:beef:cafe:face:feed/56"),
+			append(append([]byte{0xd8, 0x36, 0x82, 0x50}, ipv6...), 0x18, 0x38),
+		},
+		{
+			netip.MustParsePrefix("0.0.0.0/0"),
+			[]byte{0xd8, 0x34, 0x82, 0x00, 0x40},
+		},
+		{
+			netip.MustParseAddr("fe80::1%eth0"),
+			[]byte{
+				0xd8, 0x36, 0x83,
+				0x50, 0xfe, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+				0xf6, 0x64, 0x65, 0x74, 0x68, 0x30,
+			},
+		},
+		{net.ParseIP("192.0.2.15"), []byte{0xd8, 0x34, 0x44, 0xc0, 0x00, 0x02, 0x0f}},
+		{
+			net.IPNet{IP: net.IP{192, 0, 2, 0}, Mask: net.CIDRMask(24, 32)},
+			[]byte{0xd8, 0x34, 0x82, 0x18, 0x18, 0x43, 0xc0, 0x00, 0x02},
+		},
+	}
+	for _, c := range cases {
+		testEncoder(t, c.Value, c.Encoded)
+		if ip, ok := c.Value.(net.IP); ok {
+			testDecoder(t, c.Encoded, ip.To4())
+		} else {
+			testDecoder(t, c.Encoded, c.Value)
+		}
+		// addresses and prefixes are decoded into netip types
+		var i interface{}
+		if err := NewDecoder(bytes.NewReader(c.Encoded)).Decode(&i); err != nil {
+			t.Fatal(err)
+		}
+		switch i.(type) {
+		case netip.Addr, netip.Prefix:
+		default:
+			t.Fatalf("%#v isn't an address or a prefix", i)
+		}
+	}
+
+	var a netip.Addr
+	var p netip.Prefix
+	// prefixes can't be decoded into addresses and the other way around
+	testDecoderError(t, []byte{0xd8, 0x34, 0x82, 0x18, 0x18, 0x43, 0xc0, 0x00, 0x02}, &a)
+	testDecoderError(t, []byte{0xd8, 0x34, 0x44, 0xc0, 0x00, 0x02, 0x0f}, &p)
+	// wrong address length
+	testDecoderError(t, []byte{0xd8, 0x34, 0x43, 0xc0, 0x00, 0x02}, &a)
+	// prefix with trailing zero bytes or host bits
+	testDecoderError(t, []byte{0xd8, 0x34, 0x82, 0x18, 0x18, 0x44, 0xc0, 0x00, 0x02, 0x00}, &p)
+	testDecoderError(t, []byte{0xd8, 0x34, 0x82, 0x18, 0x10, 0x43, 0xc0, 0x00, 0x02}, &p)
+	// prefix length too long
+	testDecoderError(t, []byte{0xd8, 0x34, 0x82, 0x18, 0x21, 0x43, 0xc0, 0x00, 0x02}, &p)
+
+	// untagged byte strings are still decoded into net.IP
+	testDecoder(t, []byte{0x44, 0xc0, 0x00, 0x02, 0x0f}, net.IP{192, 0, 2, 15})
+
+	// conversion options don't apply to addresses
+	testEncoder(t,
+		struct {
+			IP net.IP `cbor:"ip,hex"`
+		}{net.IP{192, 0, 2, 15}},
+		[]byte{0xa1, 0x62, 0x69, 0x70, 0xd8, 0x34, 0x44, 0xc0, 0x00, 0x02, 0x0f},
+	)
+}
@@ -0,0 +1,74 @@
+package cbor
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+)
+
+// RawMessage is a raw encoded CBOR item. It's written as is by the encoder,
+// and the decoder stores the exact encoding of an item in it. It can be used to
+// delay the decoding of an item, or to embed an item that's already encoded.
+type RawMessage []byte
+
+var rawMessageType = reflect.TypeOf(RawMessage(nil))
+
+var ErrInvalidRawMessage = errors.New("cbor: RawMessage isn't a single well-formed item")
+
+// validRawMessage reports whether raw contains a single well-formed item
+func validRawMessage(raw []byte) bool {
+	var r = bytes.NewReader(raw)
+	if err := NewDecoder(r).skip(); err != nil {
+		return false
+	}
+	return r.Len() == 0
+}
+
+// writeRawMessage writes the encoded item raw, an empty RawMessage is written
+// as null
+func (e *Encoder) writeRawMessage(raw []byte) error {
+	if len(raw) == 0 {
+		return e.writeHeader(majorSimpleValue, simpleValueNil)
+	}
+	if !validRawMessage(raw) {
+		return ErrInvalidRawMessage
+	}
+	if e.namespace != nil {
+		if err := e.addRawStringRefs(raw); err != nil {
+			return err
+		}
+	}
+	_, err := e.w.Write(raw)
+	return err
+}
+
+// decodeRawMessage reads the rest of the item with the given header, and
+// stores a copy of its encoded form in v
+func (d *Decoder) decodeRawMessage(major, minor byte, v reflect.Value) error {
+	raw, err := d.record(major, minor, d.skipValue)
+	if err != nil {
+		return err
+	}
+	v.SetBytes(raw)
+	return nil
+}
+
+// record calls f to read the rest of the item with the given header, and
+// returns a copy of the item's encoded form
+func (d *Decoder) record(major, minor byte, f func(major, minor byte) error) ([]byte, error) {
+	var start = len(d.raw)
+	if d.recording > 0 {
+		// the header was already recorded
+		start--
+	} else {
+		d.raw = append(d.raw, major<<5|minor)
+	}
+	d.recording++
+	var err = f(major, minor)
+	d.recording--
+	var raw = append(RawMessage(nil), d.raw[start:]...)
+	if d.recording == 0 {
+		d.raw = d.raw[:0]
+	}
+	return raw, err
+}
@@ -0,0 +1,136 @@
+package cbor
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+)
+
+// Set is a set of values, it's encoded as an array with the tag 258. The
+// elements are sorted by their encoding, that way the same set is always
+// encoded the same way.
+type Set[T comparable] map[T]struct{}
+
+func (Set[T]) set() {}
+
+// setMap is implemented by all the Set types
+type setMap interface {
+	set()
+}
+
+var setMapType = reflect.TypeOf((*setMap)(nil)).Elem()
+
+// isSet reports whether t is a Set type
+func isSet(t reflect.Type) bool {
+	return t.Kind() == reflect.Map && t.Implements(setMapType)
+}
+
+// isSetShaped reports whether t is a map used as a set: its values are empty
+// structs or booleans
+func isSetShaped(t reflect.Type) bool {
+	if t.Kind() != reflect.Map {
+		return false
+	}
+	var elem = t.Elem()
+	return (elem.Kind() == reflect.Struct && elem.NumField() == 0) || elem.Kind() == reflect.Bool
+}
+
+// SetMapsAsSets enables or disables the encoding of maps with struct{} or bool
+// values as sets with the tag 258. Only the keys with a true value are in the
+// set for bool values.
+func (e *Encoder) SetMapsAsSets(enabled bool) {
+	e.mapsAsSets = enabled
+}
+
+// writeSet writes the map x as an array of its keys with the tag 258
+func (e *Encoder) writeSet(x reflect.Value) error {
+	var keys = x.MapKeys()
+	if x.Type().Elem().Kind() == reflect.Bool {
+		var n = 0
+		for _, key := range keys {
+			if x.MapIndex(key).Bool() {
+				keys[n] = key
+				n++
+			}
+		}
+		keys = keys[:n]
+	}
+
+	// sort the keys by their encoding, references to shared values and
+	// strings aren't used to sort since they depend on what's written before
+	var encoded = make([][]byte, len(keys))
+	for i, key := range keys {
+		var buffer bytes.Buffer
+		var inner = *e
+		inner.w = &buffer
+		inner.shared = nil
+		inner.namespace = nil
+		if err := inner.encode(key); err != nil {
+			return err
+		}
+		encoded[i] = buffer.Bytes()
+	}
+	sort.Sort(&setKeys{keys, encoded})
+
+	if err := e.writeTag(tagSet); err != nil {
+		return err
+	}
+	if err := e.writeInteger(majorArray, uint64(len(keys))); err != nil {
+		return err
+	}
+	for i, key := range keys {
+		if e.shared == nil && e.namespace == nil {
+			if _, err := e.w.Write(encoded[i]); err != nil {
+				return err
+			}
+		} else if err := e.encode(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setKeys sorts the keys of a set by their encoding
+type setKeys struct {
+	keys    []reflect.Value
+	encoded [][]byte
+}
+
+func (s *setKeys) Len() int {
+	return len(s.keys)
+}
+
+func (s *setKeys) Less(i, j int) bool {
+	return bytes.Compare(s.encoded[i], s.encoded[j]) < 0
+}
+
+func (s *setKeys) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.encoded[i], s.encoded[j] = s.encoded[j], s.encoded[i]
+}
+
+// decodeSet decodes an array into v, a map used as a set
+func (d *Decoder) decodeSet(major, minor byte, v reflect.Value) error {
+	if major != majorArray {
+		return d.typeError(major, minor, v)
+	}
+	var t = v.Type()
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(t))
+	}
+	var value = reflect.New(t.Elem()).Elem()
+	if value.Kind() == reflect.Bool {
+		value.SetBool(true)
+	}
+	return d.readItems(minor, func(major, minor byte) error {
+		var key = reflect.New(t.Key()).Elem()
+		if err := d.decodeValue(major, minor, key); err != nil {
+			return err
+		}
+		if err := makeHashable(key); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, value)
+		return nil
+	})
+}
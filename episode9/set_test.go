@@ -0,0 +1,52 @@
+package cbor
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	// the elements are sorted by their encoding
+	var encoded = []byte{
+		0xd9, 0x01, 0x02, 0x83, 0x61, 0x62, 0x61, 0x63, 0x62, 0x61, 0x61,
+	}
+	testEncoder(t, Set[string]{"aa": {}, "c": {}, "b": {}}, encoded)
+	testDecoder(t, encoded, Set[string]{"aa": {}, "c": {}, "b": {}})
+	testEncoder(t, Set[int]{}, []byte{0xd9, 0x01, 0x02, 0x80})
+
+	// maps are encoded as sets with an option
+	testEncoder(t, map[string]struct{}{"a": {}}, []byte{0xa1, 0x61, 0x61, 0xa0})
+	var buffer bytes.Buffer
+	var e = NewEncoder(&buffer)
+	e.SetMapsAsSets(true)
+	for _, v := range []interface{}{
+		map[string]struct{}{"aa": {}, "c": {}, "b": {}},
+		map[string]bool{"aa": true, "c": true, "b": true, "d": false},
+	} {
+		buffer.Reset()
+		if err := e.Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buffer.Bytes(), encoded) {
+			t.Fatalf("%#v != %#v", buffer.Bytes(), encoded)
+		}
+	}
+
+	// into maps, slices, and interfaces
+	testDecoder(t, encoded, map[string]struct{}{"aa": {}, "c": {}, "b": {}})
+	testDecoder(t, encoded, map[string]bool{"aa": true, "c": true, "b": true})
+	testDecoder(t, encoded, []string{"b", "c", "aa"})
+	testDecoder(t, []byte{0x82, 0x01, 0x02}, map[int]struct{}{1: {}, 2: {}})
+	var i interface{}
+	if err := NewDecoder(bytes.NewReader(encoded)).Decode(&i); err != nil {
+		t.Fatal(err)
+	}
+	var expected = Set[interface{}]{"aa": {}, "c": {}, "b": {}}
+	if !reflect.DeepEqual(i, expected) {
+		t.Fatalf("%#v != %#v", i, expected)
+	}
+
+	testDecoderError(t, []byte{0xd9, 0x01, 0x02, 0xa0}, &Set[int]{})
+	testDecoderError(t, []byte{0xd9, 0x01, 0x02, 0x81, 0xa0}, &i)
+}
@@ -0,0 +1,164 @@
+package cbor
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// sharedKey identifies a pointer, the type is needed because a struct and its
+// first field have the same address
+type sharedKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// findShared returns the pointers found more than once in x. They're mapped to
+// -1, the index of their value once it's written. Maps and slices containing
+// themselves, and values nested deeper than maxDepth aren't walked, the
+// encoder reports them.
+func findShared(x reflect.Value, maxDepth int) map[sharedKey]int {
+	var counts = make(map[sharedKey]int)
+	var path = make(map[pathKey]struct{})
+	var walk func(x reflect.Value, depth int)
+	walk = func(x reflect.Value, depth int) {
+		if depth > maxDepth {
+			return
+		}
+		switch x.Kind() {
+		case reflect.Ptr:
+			if x.IsNil() {
+				return
+			}
+			var key = sharedKey{x.Pointer(), x.Type()}
+			counts[key]++
+			// only walk the value the first time to stop on cycles
+			if counts[key] == 1 {
+				walk(x.Elem(), depth+1)
+			}
+		case reflect.Interface:
+			if !x.IsNil() {
+				walk(x.Elem(), depth+1)
+			}
+		case reflect.Struct:
+			for i := 0; i < x.NumField(); i++ {
+				walk(x.Field(i), depth+1)
+			}
+		case reflect.Slice, reflect.Array:
+			if isByte(x.Type().Elem()) {
+				return
+			}
+			if x.Kind() == reflect.Slice {
+				if x.Len() == 0 {
+					return
+				}
+				var key = newPathKey(x)
+				if _, ok := path[key]; ok {
+					return
+				}
+				path[key] = struct{}{}
+				defer delete(path, key)
+			}
+			for i := 0; i < x.Len(); i++ {
+				walk(x.Index(i), depth+1)
+			}
+		case reflect.Map:
+			if x.IsNil() {
+				return
+			}
+			var key = newPathKey(x)
+			if _, ok := path[key]; ok {
+				return
+			}
+			path[key] = struct{}{}
+			defer delete(path, key)
+			for _, key := range x.MapKeys() {
+				walk(key, depth+1)
+				walk(x.MapIndex(key), depth+1)
+			}
+		}
+	}
+	walk(x, 1)
+
+	var shared = make(map[sharedKey]int)
+	for key, count := range counts {
+		if count > 1 {
+			shared[key] = -1
+		}
+	}
+	return shared
+}
+
+// writeShared writes the pointer x if it's shared: the first time its value is
+// written with the tag 28, after that a reference to the value with the tag 29
+// is written. done is false if x isn't shared.
+func (e *Encoder) writeShared(x reflect.Value) (done bool, err error) {
+	var key = sharedKey{x.Pointer(), x.Type()}
+	index, ok := e.shared[key]
+	if !ok {
+		return false, nil
+	}
+	if index >= 0 {
+		if err := e.writeTag(tagSharedRef); err != nil {
+			return true, err
+		}
+		return true, e.writeInteger(majorPositiveInteger, uint64(index))
+	}
+	e.shared[key] = e.sharedCount
+	e.sharedCount++
+	if err := e.writeTag(tagShareable); err != nil {
+		return true, err
+	}
+	return true, e.encode(x.Elem())
+}
+
+// decodeShareable decodes the content of a tag 28 into v, and remembers v to
+// resolve the references to it. When v is a pointer references will point to
+// the same value.
+func (d *Decoder) decodeShareable(major, minor byte, v reflect.Value) error {
+	var index = len(d.shared)
+	d.shared = append(d.shared, reflect.Value{})
+	switch {
+	case v.Kind() == reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		// the value is remembered before its content is decoded, that way
+		// the content can refer to itself
+		d.shared[index] = reflect.ValueOf(v.Interface())
+		return d.decodeValue(major, minor, v.Elem())
+	case v.Kind() == reflect.Interface:
+		if err := d.decodeValue(major, minor, v); err != nil {
+			return err
+		}
+		d.shared[index] = v.Elem()
+		return nil
+	case v.CanAddr():
+		d.shared[index] = v.Addr()
+	}
+	return d.decodeValue(major, minor, v)
+}
+
+// decodeSharedRef decodes the content of a tag 29, a reference to a shared
+// value, into v
+func (d *Decoder) decodeSharedRef(major, minor byte, v reflect.Value) error {
+	if major != majorPositiveInteger {
+		return d.typeError(major, minor, v)
+	}
+	n, err := d.readArgument(minor)
+	if err != nil {
+		return err
+	}
+	if n >= uint64(len(d.shared)) || !d.shared[n].IsValid() {
+		return fmt.Errorf("cbor: invalid shared value reference %d", n)
+	}
+	var ref = d.shared[n]
+	switch {
+	case ref.Type().AssignableTo(v.Type()):
+		v.Set(ref)
+	case ref.Kind() == reflect.Ptr && ref.Type().Elem().AssignableTo(v.Type()):
+		v.Set(ref.Elem())
+	default:
+		return &UnmarshalTypeError{Value: "shared value reference", Type: v.Type()}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package cbor
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestValueSharing(t *testing.T) {
+	type Pair struct {
+		A *int `cbor:"a"`
+		B *int `cbor:"b"`
+	}
+	var n = 1
+	var p = Pair{A: &n, B: &n}
+
+	// without value sharing the value is written twice
+	testEncoder(t, p, []byte{0xa2, 0x61, 0x61, 0x01, 0x61, 0x62, 0x01})
+
+	var encoded = []byte{
+		0xa2,
+		0x61, 0x61, 0xd8, 0x1c, 0x01,
+		0x61, 0x62, 0xd8, 0x1d, 0x00,
+	}
+	var buffer bytes.Buffer
+	var e = NewEncoder(&buffer)
+	e.SetValueSharing(true)
+	if err := e.Encode(p); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buffer.Bytes(), encoded) {
+		t.Fatalf("%#v != %#v", buffer.Bytes(), encoded)
+	}
+
+	var q Pair
+	if err := NewDecoder(bytes.NewReader(encoded)).Decode(&q); err != nil {
+		t.Fatal(err)
+	}
+	if q.A != q.B || *q.A != 1 {
+		t.Fatalf("%#v isn't shared", q)
+	}
+
+	// references can be decoded into non-pointers
+	var i struct {
+		A int `cbor:"a"`
+		B int `cbor:"b"`
+	}
+	if err := NewDecoder(bytes.NewReader(encoded)).Decode(&i); err != nil {
+		t.Fatal(err)
+	}
+	if i.A != 1 || i.B != 1 {
+		t.Fatalf("%#v != {1 1}", i)
+	}
+
+	// skipped values keep their index
+	i.A, i.B = 0, 0
+	if err := NewDecoder(bytes.NewReader([]byte{
+		0xa3,
+		0x61, 0x78, 0xd8, 0x1c, 0x01,
+		0x61, 0x61, 0xd8, 0x1c, 0x02,
+		0x61, 0x62, 0xd8, 0x1d, 0x01,
+	})).Decode(&i); err != nil {
+		t.Fatal(err)
+	}
+	if i.A != 2 || i.B != 2 {
+		t.Fatalf("%#v != {2 2}", i)
+	}
+
+	// into interface{}
+	var v interface{}
+	if err := NewDecoder(bytes.NewReader(
+		[]byte{0x82, 0xd8, 0x1c, 0x81, 0x01, 0xd8, 0x1d, 0x00},
+	)).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	var expected = []interface{}{[]interface{}{uint64(1)}, []interface{}{uint64(1)}}
+	if !reflect.DeepEqual(v, expected) {
+		t.Fatalf("%#v != %#v", v, expected)
+	}
+
+	// references must point to a value decoded before
+	testDecoderError(t, []byte{0xd8, 0x1d, 0x00}, &v)
+	testDecoderError(t, []byte{0x82, 0xd8, 0x1c, 0x01, 0xd8, 0x1d, 0x01}, &v)
+	testDecoderError(t, []byte{0x82, 0xd8, 0x1c, 0x01, 0xd8, 0x1d, 0x60}, &v)
+}
+
+func TestValueSharingCycle(t *testing.T) {
+	type Node struct {
+		Next *Node `cbor:"next"`
+	}
+	var n = &Node{}
+	n.Next = n
+
+	var encoded = []byte{
+		0xd8, 0x1c, 0xa1, 0x64, 0x6e, 0x65, 0x78, 0x74, 0xd8, 0x1d, 0x00,
+	}
+	var buffer bytes.Buffer
+	var e = NewEncoder(&buffer)
+	e.SetValueSharing(true)
+	if err := e.Encode(n); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buffer.Bytes(), encoded) {
+		t.Fatalf("%#v != %#v", buffer.Bytes(), encoded)
+	}
+
+	var m *Node
+	if err := NewDecoder(bytes.NewReader(encoded)).Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m == nil || m.Next != m {
+		t.Fatalf("%#v isn't cyclic", m)
+	}
+}
@@ -0,0 +1,43 @@
+package cbor
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// SimpleValue is a CBOR simple value. false, true, null, and undefined are
+// the simple values 20 to 23, values from 0 to 19 and from 32 to 255 are
+// unassigned. Values from 24 to 31 are reserved, they can't be encoded.
+//
+// Unassigned simple values are decoded as SimpleValue into an empty
+// interface.
+type SimpleValue uint8
+
+// Undefined is the undefined simple value, it's decoded as nil into pointers
+// and interfaces
+const Undefined SimpleValue = simpleValueUndefined
+
+var simpleValueType = reflect.TypeOf(SimpleValue(0))
+
+func (e *Encoder) writeSimpleValue(v SimpleValue) error {
+	switch {
+	case v < minorInt8:
+		return e.writeHeader(majorSimpleValue, byte(v))
+	case v < 32:
+		return &UnsupportedValueError{reflect.ValueOf(v), fmt.Sprintf("reserved simple value %d", v)}
+	}
+	return e.writeHeaderInteger(majorSimpleValue, minorInt8, uint8(v))
+}
+
+// decodeSimpleValueType decodes a simple value into the SimpleValue v
+func (d *Decoder) decodeSimpleValueType(major, minor byte, v reflect.Value) error {
+	if major != majorSimpleValue || minor == minorFloat16 || minor == minorFloat32 || minor == minorFloat64 {
+		return d.typeError(major, minor, v)
+	}
+	n, err := d.readSimpleValue(minor)
+	if err != nil {
+		return err
+	}
+	v.SetUint(n)
+	return nil
+}
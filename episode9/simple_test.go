@@ -0,0 +1,51 @@
+package cbor
+
+import (
+	"io"
+	"testing"
+)
+
+func TestSimpleValue(t *testing.T) {
+	var cases = []struct {
+		Value   SimpleValue
+		Encoded []byte
+	}{
+		{0, []byte{0xe0}},
+		{16, []byte{0xf0}},
+		{simpleValueFalse, []byte{0xf4}},
+		{Undefined, []byte{0xf7}},
+		{32, []byte{0xf8, 0x20}},
+		{255, []byte{0xf8, 0xff}},
+	}
+	for _, c := range cases {
+		testEncoder(t, c.Value, c.Encoded)
+		testDecoder(t, c.Encoded, c.Value)
+	}
+	testEncoderUnsupported(t, NewEncoder(io.Discard), SimpleValue(24))
+	testEncoderUnsupported(t, NewEncoder(io.Discard), SimpleValue(31))
+
+	// slices and arrays of simple values aren't byte strings
+	var array = []byte{0x83, 0xf0, 0xf7, 0xf8, 0x20}
+	testEncoder(t, []SimpleValue{16, Undefined, 32}, array)
+	testEncoder(t, [3]SimpleValue{16, Undefined, 32}, array)
+	testDecoder(t, array, []SimpleValue{16, Undefined, 32})
+	testDecoder(t, array, [3]SimpleValue{16, Undefined, 32})
+	testEncoderUnsupported(t, NewEncoder(io.Discard), []SimpleValue{16, 24})
+	testEncoderUnsupported(t, NewEncoder(io.Discard), [1]SimpleValue{31})
+
+	// into interface{}, undefined is nil like null
+	testDecoder(t, []byte{0x83, 0xf0, 0xf8, 0x20, 0xf7}, []interface{}{SimpleValue(16), SimpleValue(32), nil})
+	testDecoder(t, []byte{0xf7}, (*SimpleValue)(nil))
+
+	var v SimpleValue
+	var i interface{}
+	// the two bytes form is only used for values from 32
+	testDecoderError(t, []byte{0xf8, 0x18}, &v)
+	testDecoderError(t, []byte{0xf8, 0x1f}, &i)
+	testDecoderError(t, []byte{0xf8, 0x10}, &i)
+	testDecoderError(t, []byte{0xfc}, &v)
+	testDecoderError(t, []byte{0xf9, 0x00, 0x00}, &v)
+	testDecoderError(t, []byte{0x01}, &v)
+	var s []SimpleValue
+	testDecoderError(t, []byte{0x43, 0x10, 0x17, 0x20}, &s)
+}
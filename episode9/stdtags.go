@@ -0,0 +1,157 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+// UUID is a binary UUID, it's encoded as a byte string with the tag 37
+type UUID [16]byte
+
+// String returns the UUID in its canonical form:
+// xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
+func (u UUID) String() string {
+	var s = hex.EncodeToString(u[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+// Base64URL is binary data encoded as a base64url text string without padding
+// with the tag 33
+type Base64URL []byte
+
+// Base64 is binary data encoded as a base64 text string with the tag 34
+type Base64 []byte
+
+var (
+	urlType         = reflect.TypeOf(url.URL{})
+	regexpType      = reflect.TypeOf(regexp.Regexp{})
+	mailMessageType = reflect.TypeOf(mail.Message{})
+	uuidType        = reflect.TypeOf(UUID{})
+	base64URLType   = reflect.TypeOf(Base64URL(nil))
+	base64Type      = reflect.TypeOf(Base64(nil))
+)
+
+// writeTaggedString writes the text string s with the tag
+func (e *Encoder) writeTaggedString(tag uint64, s string) error {
+	if err := e.writeTag(tag); err != nil {
+		return err
+	}
+	return e.writeUnicodeString(s)
+}
+
+// writeMailMessage writes the message's header followed by its body as a MIME
+// message with the tag 36. The header fields are sorted, and the body is read
+// until the end.
+func (e *Encoder) writeMailMessage(m *mail.Message) error {
+	var b bytes.Buffer
+	var keys = make([]string, 0, len(m.Header))
+	for key := range m.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range m.Header[key] {
+			fmt.Fprintf(&b, "%s: %s\r\n", key, value)
+		}
+	}
+	b.WriteString("\r\n")
+	if m.Body != nil {
+		if _, err := io.Copy(&b, m.Body); err != nil {
+			return err
+		}
+	}
+	return e.writeTaggedString(tagMIME, b.String())
+}
+
+func (e *Encoder) writeUUID(u UUID) error {
+	if err := e.writeTag(tagUUID); err != nil {
+		return err
+	}
+	return e.writeByteString(u[:])
+}
+
+// readTaggedString reads a text string with the tag expected, or without tag
+func (d *Decoder) readTaggedString(tag, expected uint64, major, minor byte, v reflect.Value) (string, error) {
+	if (tag != noTag && tag != expected) || major != majorUnicodeString {
+		return "", d.typeError(major, minor, v)
+	}
+	s, err := d.readString(major, minor)
+	return string(s), err
+}
+
+// decodeStandardTag decodes the items with the standard tags 32 to 37 into v,
+// the content of the tag is validated
+func (d *Decoder) decodeStandardTag(tag uint64, major, minor byte, v reflect.Value) error {
+	var x interface{}
+	switch v.Type() {
+	case urlType:
+		s, err := d.readTaggedString(tag, tagURI, major, minor, v)
+		if err != nil {
+			return err
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return fmt.Errorf("cbor: invalid URI: %v", err)
+		}
+		x = *u
+	case regexpType:
+		s, err := d.readTaggedString(tag, tagRegexp, major, minor, v)
+		if err != nil {
+			return err
+		}
+		r, err := regexp.Compile(s)
+		if err != nil {
+			return fmt.Errorf("cbor: invalid regular expression: %v", err)
+		}
+		v.Set(reflect.ValueOf(r).Elem())
+		return nil
+	case mailMessageType:
+		s, err := d.readTaggedString(tag, tagMIME, major, minor, v)
+		if err != nil {
+			return err
+		}
+		m, err := mail.ReadMessage(bytes.NewBufferString(s))
+		if err != nil {
+			return fmt.Errorf("cbor: invalid MIME message: %v", err)
+		}
+		x = *m
+	case base64URLType, base64Type:
+		var expected, encoding = uint64(tagBase64URL), base64.RawURLEncoding.Strict()
+		if v.Type() == base64Type {
+			expected, encoding = tagBase64, base64.StdEncoding.Strict()
+		}
+		s, err := d.readTaggedString(tag, expected, major, minor, v)
+		if err != nil {
+			return err
+		}
+		b, err := encoding.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("cbor: invalid base64 string: %v", err)
+		}
+		v.SetBytes(b)
+		return nil
+	case uuidType:
+		if (tag != noTag && tag != tagUUID) || major != majorByteString {
+			return d.typeError(major, minor, v)
+		}
+		b, err := d.readString(major, minor)
+		if err != nil {
+			return err
+		}
+		if len(b) != len(UUID{}) {
+			return fmt.Errorf("cbor: invalid UUID length: %d", len(b))
+		}
+		reflect.Copy(v, reflect.ValueOf(b))
+		return nil
+	}
+	v.Set(reflect.ValueOf(x))
+	return nil
+}
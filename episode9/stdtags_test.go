@@ -0,0 +1,107 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"testing"
+)
+
+func TestURI(t *testing.T) {
+	var u, _ = url.Parse("http://www.example.com")
+	var encoded = []byte{
+		0xd8, 0x20, 0x76, 0x68, 0x74, 0x74, 0x70, 0x3a, 0x2f, 0x2f, 0x77,
+		0x77, 0x77, 0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e,
+		0x63, 0x6f, 0x6d,
+	}
+	testEncoder(t, u, encoded)
+	testDecoder(t, encoded, u)
+	testDecoder(t, encoded[2:], *u)
+	testDecoder(t, append([]byte{0x81}, encoded...), []interface{}{u})
+
+	var x url.URL
+	testDecoderError(t, []byte{0xd8, 0x20, 0x63, 0x3a, 0x2f, 0x25}, &x)
+	testDecoderError(t, []byte{0xd8, 0x20, 0x01}, &x)
+}
+
+func TestRegexp(t *testing.T) {
+	var r = regexp.MustCompile("a+b")
+	var encoded = []byte{0xd8, 0x23, 0x63, 0x61, 0x2b, 0x62}
+	testEncoder(t, r, encoded)
+
+	var x *regexp.Regexp
+	if err := NewDecoder(bytes.NewReader(encoded)).Decode(&x); err != nil {
+		t.Fatal(err)
+	}
+	if x.String() != "a+b" || !x.MatchString("aab") {
+		t.Fatalf("%v != a+b", x)
+	}
+	var i interface{}
+	if err := NewDecoder(bytes.NewReader(encoded)).Decode(&i); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := i.(*regexp.Regexp); !ok {
+		t.Fatalf("%#v isn't a *regexp.Regexp", i)
+	}
+	// invalid regular expression
+	testDecoderError(t, []byte{0xd8, 0x23, 0x61, 0x28}, &x)
+}
+
+func TestMIME(t *testing.T) {
+	var m = mail.Message{
+		Header: mail.Header{"Subject": []string{"Hi"}},
+		Body:   bytes.NewBufferString("hello"),
+	}
+	var encoded = []byte{
+		0xd8, 0x24, 0x74, 0x53, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x3a,
+		0x20, 0x48, 0x69, 0x0d, 0x0a, 0x0d, 0x0a, 0x68, 0x65, 0x6c, 0x6c,
+		0x6f,
+	}
+	testEncoder(t, &m, encoded)
+
+	var x *mail.Message
+	if err := NewDecoder(bytes.NewReader(encoded)).Decode(&x); err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(x.Body); x.Header.Get("Subject") != "Hi" || string(body) != "hello" {
+		t.Fatalf("%#v, %q", x.Header, body)
+	}
+	testDecoderError(t, []byte{0xd8, 0x24, 0x61, 0x3a}, &x)
+}
+
+func TestUUID(t *testing.T) {
+	var u = UUID{
+		0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3,
+		0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00,
+	}
+	if u.String() != "123e4567-e89b-12d3-a456-426614174000" {
+		t.Fatalf("%v != 123e4567-e89b-12d3-a456-426614174000", u)
+	}
+	var encoded = append([]byte{0xd8, 0x25, 0x50}, u[:]...)
+	testEncoder(t, u, encoded)
+	testDecoder(t, encoded, u)
+	testDecoder(t, append([]byte{0x81}, encoded...), []interface{}{u})
+
+	var x UUID
+	testDecoderError(t, []byte{0xd8, 0x25, 0x42, 0x01, 0x02}, &x)
+}
+
+func TestBase64(t *testing.T) {
+	var data = []byte{0xfb, 0xff}
+	var urlEncoded = []byte{0xd8, 0x21, 0x63, 0x2d, 0x5f, 0x38}
+	var stdEncoded = []byte{0xd8, 0x22, 0x64, 0x2b, 0x2f, 0x38, 0x3d}
+
+	testEncoder(t, Base64URL(data), urlEncoded)
+	testEncoder(t, Base64(data), stdEncoded)
+	testDecoder(t, urlEncoded, Base64URL(data))
+	testDecoder(t, stdEncoded, Base64(data))
+	testDecoder(t, append([]byte{0x81}, urlEncoded...), []interface{}{Base64URL(data)})
+
+	var x Base64URL
+	// padding isn't allowed with base64url
+	testDecoderError(t, []byte{0xd8, 0x21, 0x64, 0x2d, 0x5f, 0x38, 0x3d}, &x)
+	var y Base64
+	testDecoderError(t, []byte{0xd8, 0x22, 0x63, 0x2b, 0x2f, 0x38}, &y)
+}
@@ -0,0 +1,166 @@
+package cbor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// stringRefKey identifies a string in a namespace, text and byte strings with
+// the same content are different strings
+type stringRefKey struct {
+	major byte
+	s     string
+}
+
+// stringRef is a string of a namespace, as seen by the decoder
+type stringRef struct {
+	major byte
+	s     []byte
+}
+
+// stringRefMinLength returns the minimum length of a string added to a
+// namespace containing n strings: a string is added only if it isn't shorter
+// than a reference to it.
+func stringRefMinLength(n uint64) int {
+	switch {
+	case n < 24:
+		return 3
+	case n < 256:
+		return 4
+	case n < 65536:
+		return 5
+	case n < 1<<32:
+		return 7
+	}
+	return 11
+}
+
+// SetStringRefs enables or disables string references: values are written in
+// a namespace with the tag 256, and strings found more than once are written
+// the first time, and as a reference with the tag 25 after that.
+func (e *Encoder) SetStringRefs(enabled bool) {
+	e.stringRefs = enabled
+}
+
+// writeStringRef writes a reference to the string s if it's in the
+// namespace, if it isn't s is added to the namespace when it's long enough.
+// done is false if s must be written.
+func (e *Encoder) writeStringRef(major byte, s string) (done bool, err error) {
+	if e.namespace == nil {
+		return false, nil
+	}
+	var key = stringRefKey{major, s}
+	if index, ok := e.namespace[key]; ok {
+		if err := e.writeTag(tagStringRef); err != nil {
+			return true, err
+		}
+		return true, e.writeInteger(majorPositiveInteger, index)
+	}
+	if len(s) >= stringRefMinLength(e.namespaceLen) {
+		e.namespace[key] = e.namespaceLen
+		e.namespaceLen++
+	}
+	return false, nil
+}
+
+// addRawStringRefs adds the strings of the raw message to the namespace like
+// the decoder does when it reads it. References in raw messages can't be
+// resolved by the encoder.
+func (e *Encoder) addRawStringRefs(raw []byte) error {
+	var d = NewDecoder(bytes.NewReader(raw))
+	d.namespace = make([]stringRef, e.namespaceLen)
+	if err := d.skip(); err != nil {
+		return err
+	}
+	for _, ref := range d.namespace[e.namespaceLen:] {
+		var key = stringRefKey{ref.major, string(ref.s)}
+		if _, ok := e.namespace[key]; !ok {
+			e.namespace[key] = e.namespaceLen
+		}
+		e.namespaceLen++
+	}
+	return nil
+}
+
+// addStringRef adds the string s read from the input to the namespace if it's
+// long enough
+func (d *Decoder) addStringRef(major byte, s []byte) {
+	if d.namespace != nil && len(s) >= stringRefMinLength(uint64(len(d.namespace))) {
+		d.namespace = append(d.namespace, stringRef{major, s})
+	}
+}
+
+// readStringRef reads the rest of a tag with the given additional
+// information. If it's a string reference the header of the string is
+// returned, and the next call to readString returns the string. Other tags are
+// returned as is.
+func (d *Decoder) readStringRef(minor byte) (byte, byte, error) {
+	var b = make([]byte, 1<<(minor-minorInt8))
+	if _, err := d.readFull(b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, 0, err
+	}
+	var tag uint64
+	for _, c := range b {
+		tag = tag<<8 | uint64(c)
+	}
+	if tag != tagStringRef {
+		// put back the tag number, it's read by the caller
+		d.unread(b)
+		return majorTag, minor, nil
+	}
+
+	// the reference is replaced by the string in recordings, that way raw
+	// messages don't contain references
+	var start = len(d.raw) - 1
+	major, minor, err := d.readItemHeader()
+	if err != nil {
+		return 0, 0, err
+	}
+	if major != majorPositiveInteger {
+		return 0, 0, ErrMalformed
+	}
+	index, err := d.readArgument(minor)
+	if err != nil {
+		return 0, 0, err
+	}
+	if index >= uint64(len(d.namespace)) || d.namespace[index].major == 0 {
+		return 0, 0, fmt.Errorf("cbor: invalid string reference %d", index)
+	}
+	var ref = d.namespace[index]
+	var header bytes.Buffer
+	if err := NewEncoder(&header).writeInteger(ref.major, uint64(len(ref.s))); err != nil {
+		return 0, 0, err
+	}
+	if d.recording > 0 {
+		d.raw = append(d.raw[:start], header.Bytes()[0])
+	}
+	d.refArgument = header.Bytes()[1:]
+	d.ref = ref.s
+	return ref.major, header.Bytes()[0] & 0x1f, nil
+}
+
+// readPendingStringRef returns a copy of the string returned by the last
+// string reference
+func (d *Decoder) readPendingStringRef() []byte {
+	var s = append([]byte(nil), d.ref...)
+	if d.recording > 0 {
+		d.raw = append(d.raw, d.refArgument...)
+		d.raw = append(d.raw, s...)
+	}
+	d.ref, d.refArgument = nil, nil
+	return s
+}
+
+// enterNamespace starts a new namespace for the content of a tag 256, and
+// returns a function restoring the outer namespace
+func (d *Decoder) enterNamespace() func() {
+	var outer = d.namespace
+	d.namespace = []stringRef{}
+	return func() {
+		d.namespace = outer
+	}
+}
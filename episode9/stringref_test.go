@@ -0,0 +1,133 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func testStringRefEncoder(t *testing.T, v interface{}, expected []byte) {
+	t.Helper()
+	var buffer bytes.Buffer
+	var e = NewEncoder(&buffer)
+	e.SetStringRefs(true)
+	if err := e.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buffer.Bytes(), expected) {
+		t.Fatalf("%#v != %#v", buffer.Bytes(), expected)
+	}
+}
+
+func TestStringRef(t *testing.T) {
+	type Entry struct {
+		Name string `cbor:"name"`
+		Kind string `cbor:"kind"`
+	}
+	var entries = []Entry{{"alpha", "enum"}, {"alpha", "enum"}}
+	var encoded = []byte{
+		0xd9, 0x01, 0x00, 0x82,
+		0xa2,
+		0x64, 0x6e, 0x61, 0x6d, 0x65, 0x65, 0x61, 0x6c, 0x70, 0x68, 0x61,
+		0x64, 0x6b, 0x69, 0x6e, 0x64, 0x64, 0x65, 0x6e, 0x75, 0x6d,
+		0xa2,
+		0xd8, 0x19, 0x00, 0xd8, 0x19, 0x01,
+		0xd8, 0x19, 0x02, 0xd8, 0x19, 0x03,
+	}
+	testStringRefEncoder(t, entries, encoded)
+	testDecoder(t, encoded, entries)
+
+	// text and byte strings are different strings, short strings aren't
+	// added to the namespace
+	var v = []interface{}{"abc", []byte("abc"), "ab", "abc", []byte("abc"), "ab"}
+	encoded = []byte{
+		0xd9, 0x01, 0x00, 0x86,
+		0x63, 0x61, 0x62, 0x63, 0x43, 0x61, 0x62, 0x63, 0x62, 0x61, 0x62,
+		0xd8, 0x19, 0x00, 0xd8, 0x19, 0x01, 0x62, 0x61, 0x62,
+	}
+	testStringRefEncoder(t, v, encoded)
+	var i interface{}
+	if err := NewDecoder(bytes.NewReader(encoded)).Decode(&i); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(i, v) {
+		t.Fatalf("%#v != %#v", i, v)
+	}
+}
+
+func TestStringRefMinLength(t *testing.T) {
+	// after 24 strings, strings of 3 bytes aren't added
+	var v []string
+	for i := 0; i < 24; i++ {
+		v = append(v, string([]byte{'a', 'a', 'a' + byte(i)}))
+	}
+	v = append(v, "xyz", "wxyz", "xyz", "wxyz")
+	var buffer bytes.Buffer
+	var e = NewEncoder(&buffer)
+	e.SetStringRefs(true)
+	if err := e.Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	var tail = []byte{
+		0x63, 0x78, 0x79, 0x7a, 0x64, 0x77, 0x78, 0x79, 0x7a,
+		0x63, 0x78, 0x79, 0x7a, 0xd8, 0x19, 0x18, 0x18,
+	}
+	if !bytes.HasSuffix(buffer.Bytes(), tail) {
+		t.Fatalf("%#v doesn't end with %#v", buffer.Bytes(), tail)
+	}
+	testDecoder(t, buffer.Bytes(), v)
+}
+
+func TestDecodeStringRef(t *testing.T) {
+	// references in tags
+	var u = url.URL{Scheme: "a", Opaque: "b"}
+	testDecoder(t, []byte{
+		0xd9, 0x01, 0x00, 0x82,
+		0xd8, 0x20, 0x63, 0x61, 0x3a, 0x62,
+		0xd8, 0x20, 0xd8, 0x19, 0x00,
+	}, []url.URL{u, u})
+
+	// raw messages contain the string instead of the reference
+	testDecoder(t, []byte{
+		0xd9, 0x01, 0x00, 0x82, 0x63, 0x61, 0x62, 0x63, 0xd8, 0x19, 0x00,
+	}, []RawMessage{{0x63, 0x61, 0x62, 0x63}, {0x63, 0x61, 0x62, 0x63}})
+
+	// tagged values in a namespace
+	testDecoder(t, []byte{0xd9, 0x01, 0x00, 0xd8, 0x20, 0x63, 0x61, 0x3a, 0x62}, u)
+
+	// other tags in a namespace
+	testDecoder(t, []byte{
+		0xd9, 0x01, 0x00, 0x82, 0xd8, 0x18, 0x41, 0x01, 0xd9, 0xd9, 0xf7, 0x02,
+	}, []interface{}{Embedded[interface{}]{Value: uint64(1), Raw: RawMessage{0x01}}, uint64(2)})
+
+	// the tag number put back doesn't replace the input
+	var r = bytes.NewReader([]byte{0xd9, 0x01, 0x00, 0x81, 0xd9, 0x00, 0x20, 0x61, 0x61, 0x02})
+	var d = NewDecoder(r)
+	var u2 []url.URL
+	if err := d.Decode(&u2); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := d.Decode(&n); err != nil {
+		t.Fatal(err)
+	}
+	if len(u2) != 1 || u2[0].Path != "a" || n != 2 || d.r != io.Reader(r) {
+		t.Fatalf("%#v %d", u2, n)
+	}
+
+	var i interface{}
+	// no string with this index
+	testDecoderError(t, []byte{0xd9, 0x01, 0x00, 0xd8, 0x19, 0x00}, &i)
+	// nested namespaces don't share strings
+	testDecoderError(t, []byte{
+		0xd9, 0x01, 0x00, 0x82, 0x63, 0x61, 0x62, 0x63,
+		0xd9, 0x01, 0x00, 0xd8, 0x19, 0x00,
+	}, &i)
+	// references aren't allowed in indefinite length strings
+	testDecoderError(t, []byte{
+		0xd9, 0x01, 0x00, 0x82, 0x63, 0x61, 0x62, 0x63,
+		0x7f, 0xd8, 0x19, 0x00, 0xff,
+	}, &i)
+}
@@ -66,3 +66,32 @@ func isEmptyValue(v reflect.Value) bool {
 	}
 	return false
 }
+
+// isZeroer is implemented by types that know when they are zero, like
+// time.Time.
+type isZeroer interface {
+	IsZero() bool
+}
+
+// isZeroValue reports whether v is the zero value of its type. If v has an
+// IsZero method it's used instead of comparing against the zero value.
+func isZeroValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return true
+		}
+	}
+	if v.CanInterface() {
+		if z, ok := v.Interface().(isZeroer); ok {
+			return z.IsZero()
+		}
+	}
+	// IsZero may be defined on the pointer receiver
+	if v.CanAddr() && v.Addr().CanInterface() {
+		if z, ok := v.Addr().Interface().(isZeroer); ok {
+			return z.IsZero()
+		}
+	}
+	return v.IsZero()
+}
@@ -0,0 +1,347 @@
+package cbor
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+// TimeFormat selects how the encoder writes time.Time values
+type TimeFormat int
+
+const (
+	// TimeRFC3339 writes times as RFC 3339 text strings with the tag 0
+	TimeRFC3339 TimeFormat = iota
+	// TimeUnix writes times as the number of seconds since the epoch with the
+	// tag 1, times with a fractional second are written as floats
+	TimeUnix
+	// TimeExtended writes times as a map with the tag 1001, with the number of
+	// seconds since the epoch, the fractional second, and the time zone. The
+	// fractional second is exact up to the nanosecond.
+	TimeExtended
+)
+
+// keys of extended times
+const (
+	extendedTimeBase         = 1
+	extendedTimeScale        = -1
+	extendedTimeMilliseconds = -3
+	extendedTimeMicroseconds = -6
+	extendedTimeNanoseconds  = -9
+	extendedTimeZone         = -10
+	extendedTimePicoseconds  = -12
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	dateType = reflect.TypeOf(Date{})
+)
+
+// Date is a calendar date without a time of day or a time zone. It's written
+// as a RFC 3339 full-date string with the tag 1004, or as the number of days
+// since 1970-01-01 with the tag 100 when the time format is TimeUnix.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// DateOf returns the date of t in its location
+func DateOf(t time.Time) Date {
+	var year, month, day = t.Date()
+	return Date{year, month, day}
+}
+
+// In returns the time at midnight of the date in loc
+func (d Date) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// String returns the date in the RFC 3339 full-date format: YYYY-MM-DD
+func (d Date) String() string {
+	return d.In(time.UTC).Format(time.DateOnly)
+}
+
+func (e *Encoder) writeDate(d Date) error {
+	if e.timeFormat == TimeUnix {
+		if err := e.writeTag(tagDays); err != nil {
+			return err
+		}
+		var days = d.In(time.UTC).Unix() / (24 * 60 * 60)
+		return e.encode(reflect.ValueOf(days))
+	}
+	if err := e.writeTag(tagFullDate); err != nil {
+		return err
+	}
+	return e.writeUnicodeString(d.String())
+}
+
+func (e *Encoder) writeTime(t time.Time) error {
+	if e.timeFormat == TimeExtended {
+		return e.writeExtendedTime(t)
+	}
+	if e.timeFormat == TimeUnix {
+		if err := e.writeTag(tagEpoch); err != nil {
+			return err
+		}
+		if t.Nanosecond() == 0 {
+			return e.encode(reflect.ValueOf(t.Unix()))
+		}
+		return e.writeFloat(float64(t.Unix()) + float64(t.Nanosecond())/1e9)
+	}
+	if err := e.writeTag(tagDateTime); err != nil {
+		return err
+	}
+	return e.writeUnicodeString(t.Format(time.RFC3339Nano))
+}
+
+// writeExtendedTime writes t as a map with the tag 1001. The time zone is
+// written as a hint: the name of t's location, or its offset in seconds for
+// unnamed locations.
+func (e *Encoder) writeExtendedTime(t time.Time) error {
+	var fraction, key = int64(t.Nanosecond()), int64(extendedTimeNanoseconds)
+	switch {
+	case fraction%1e6 == 0:
+		fraction, key = fraction/1e6, extendedTimeMilliseconds
+	case fraction%1e3 == 0:
+		fraction, key = fraction/1e3, extendedTimeMicroseconds
+	}
+	var zone interface{}
+	if loc := t.Location(); loc != time.UTC {
+		if name := loc.String(); name != "" && name != "Local" && name != "UTC" {
+			zone = name
+		} else {
+			_, zone = t.Zone()
+		}
+	}
+
+	var n uint64 = 1
+	if fraction != 0 {
+		n++
+	}
+	if zone != nil {
+		n++
+	}
+	if err := e.writeTag(tagExtendedTime); err != nil {
+		return err
+	}
+	if err := e.writeInteger(majorMap, n); err != nil {
+		return err
+	}
+	if err := e.encode(reflect.ValueOf(extendedTimeBase)); err != nil {
+		return err
+	}
+	if err := e.encode(reflect.ValueOf(t.Unix())); err != nil {
+		return err
+	}
+	if fraction != 0 {
+		if err := e.encode(reflect.ValueOf(key)); err != nil {
+			return err
+		}
+		if err := e.encode(reflect.ValueOf(fraction)); err != nil {
+			return err
+		}
+	}
+	if zone != nil {
+		if err := e.encode(reflect.ValueOf(extendedTimeZone)); err != nil {
+			return err
+		}
+		return e.encode(reflect.ValueOf(zone))
+	}
+	return nil
+}
+
+// decodeTime decodes a date/time string or an epoch based date/time into the
+// time.Time v. The tags 0 and 1 are optional, tag is noTag when they're
+// missing. Dates and extended times are decoded too.
+func (d *Decoder) decodeTime(tag uint64, major, minor byte, v reflect.Value) error {
+	switch {
+	case tag == noTag:
+	case tag == tagDateTime && major == majorUnicodeString:
+	case tag == tagEpoch && major != majorUnicodeString:
+	case tag == tagDays || tag == tagFullDate:
+		// dates are decoded as midnight UTC
+		var date Date
+		if err := d.decodeDate(tag, major, minor, reflect.ValueOf(&date).Elem()); err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(date.In(time.UTC)))
+		return nil
+	case tag == tagExtendedTime && major == majorMap:
+		t, err := d.readExtendedTime(minor)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	default:
+		// the tag 0 must be followed by a string, and the tag 1 by a number
+		return d.typeError(major, minor, v)
+	}
+	var t time.Time
+	switch major {
+	case majorUnicodeString:
+		s, err := d.readString(major, minor)
+		if err != nil {
+			return err
+		}
+		if t, err = time.Parse(time.RFC3339Nano, string(s)); err != nil {
+			return err
+		}
+	case majorPositiveInteger, majorNegativeInteger:
+		var seconds int64
+		if err := d.decodeInteger(major, minor, reflect.ValueOf(&seconds).Elem()); err != nil {
+			return err
+		}
+		t = time.Unix(seconds, 0).UTC()
+	case majorSimpleValue:
+		var f float64
+		if err := d.decodeSimpleValue(minor, reflect.ValueOf(&f).Elem()); err != nil {
+			return err
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) || math.Abs(f) > math.MaxInt64 {
+			return &UnmarshalTypeError{Value: "float", Type: v.Type()}
+		}
+		var seconds, frac = math.Modf(f)
+		t = time.Unix(int64(seconds), int64(math.Round(frac*1e9))).UTC()
+	default:
+		return d.typeError(major, minor, v)
+	}
+	v.Set(reflect.ValueOf(t))
+	return nil
+}
+
+// readExtendedTime reads the content of an extended time, a map with the given
+// additional information. Picoseconds must be whole nanoseconds, the time zone
+// hint is ignored if it isn't a known location.
+func (d *Decoder) readExtendedTime(minor byte) (time.Time, error) {
+	var (
+		seconds, nanoseconds int64
+		base                 bool
+		loc                  = time.UTC
+	)
+	err := d.readItems(minor, func(major, minor byte) error {
+		var key int64
+		if err := d.decodeInteger(major, minor, reflect.ValueOf(&key).Elem()); err != nil {
+			return err
+		}
+		major, minor, err := d.readItemHeader()
+		if err != nil {
+			return err
+		}
+		switch key {
+		case extendedTimeBase:
+			var t time.Time
+			if major == majorUnicodeString {
+				return d.typeError(major, minor, reflect.ValueOf(&t).Elem())
+			}
+			if err := d.decodeTime(noTag, major, minor, reflect.ValueOf(&t).Elem()); err != nil {
+				return err
+			}
+			seconds, nanoseconds, base = t.Unix(), int64(t.Nanosecond()), true
+		case extendedTimeMilliseconds, extendedTimeMicroseconds, extendedTimeNanoseconds, extendedTimePicoseconds:
+			var n uint64
+			if err := d.decodeInteger(major, minor, reflect.ValueOf(&n).Elem()); err != nil {
+				return err
+			}
+			// fractions must be less than a second
+			var valid bool
+			switch key {
+			case extendedTimeMilliseconds:
+				valid = n < 1e3
+				n *= 1e6
+			case extendedTimeMicroseconds:
+				valid = n < 1e6
+				n *= 1e3
+			case extendedTimeNanoseconds:
+				valid = n < 1e9
+			case extendedTimePicoseconds:
+				if n%1000 != 0 {
+					return ErrInexact
+				}
+				valid = n < 1e12
+				n /= 1000
+			}
+			if !valid {
+				return ErrMalformed
+			}
+			nanoseconds += int64(n)
+		case extendedTimeScale:
+			var scale uint64
+			if err := d.decodeInteger(major, minor, reflect.ValueOf(&scale).Elem()); err != nil {
+				return err
+			}
+			if scale != 0 {
+				return fmt.Errorf("cbor: unsupported time scale %d", scale)
+			}
+		case extendedTimeZone:
+			var zone interface{}
+			if err := d.decodeValue(major, minor, reflect.ValueOf(&zone).Elem()); err != nil {
+				return err
+			}
+			switch zone := zone.(type) {
+			case string:
+				if l, err := time.LoadLocation(zone); err == nil {
+					loc = l
+				}
+			case uint64:
+				loc = time.FixedZone("", int(zone))
+			case int64:
+				loc = time.FixedZone("", int(zone))
+			}
+		default:
+			// other keys aren't supported
+			return d.skipValue(major, minor)
+		}
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !base {
+		return time.Time{}, ErrMalformed
+	}
+	return time.Unix(seconds, nanoseconds).In(loc), nil
+}
+
+// decodeDate decodes a full-date string or a number of days since the epoch
+// into the Date v. The tags 1004 and 100 are optional, tag is noTag when
+// they're missing.
+func (d *Decoder) decodeDate(tag uint64, major, minor byte, v reflect.Value) error {
+	switch {
+	case tag == noTag:
+	case tag == tagFullDate && major == majorUnicodeString:
+	case tag == tagDays && (major == majorPositiveInteger || major == majorNegativeInteger):
+	default:
+		return d.typeError(major, minor, v)
+	}
+	var date Date
+	switch major {
+	case majorUnicodeString:
+		s, err := d.readString(major, minor)
+		if err != nil {
+			return err
+		}
+		t, err := time.Parse(time.DateOnly, string(s))
+		if err != nil {
+			return err
+		}
+		date = DateOf(t)
+	case majorPositiveInteger, majorNegativeInteger:
+		var days int64
+		if err := d.decodeInteger(major, minor, reflect.ValueOf(&days).Elem()); err != nil {
+			return err
+		}
+		// keep the number of seconds in an int64
+		const maxDays = math.MaxInt64 / (24 * 60 * 60)
+		if days > maxDays || days < -maxDays {
+			return fmt.Errorf("cbor: %d days overflows Go value of type %s", days, v.Type())
+		}
+		date = DateOf(time.Unix(days*24*60*60, 0).UTC())
+	default:
+		return d.typeError(major, minor, v)
+	}
+	v.Set(reflect.ValueOf(date))
+	return nil
+}
@@ -0,0 +1,188 @@
+package cbor
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestTime(t *testing.T) {
+	var date = time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)
+	var rfc3339 = []byte{
+		0xc0, 0x74, 0x32, 0x30, 0x31, 0x33, 0x2d, 0x30, 0x33, 0x2d, 0x32, 0x31,
+		0x54, 0x32, 0x30, 0x3a, 0x30, 0x34, 0x3a, 0x30, 0x30, 0x5a,
+	}
+	var epoch = []byte{0xc1, 0x1a, 0x51, 0x4b, 0x67, 0xb0}
+	var epochFloat = []byte{0xc1, 0xfb, 0x41, 0xd4, 0x52, 0xd9, 0xec, 0x20, 0x00, 0x00}
+
+	testEncoder(t, date, rfc3339)
+
+	var buffer bytes.Buffer
+	var e = NewEncoder(&buffer)
+	e.SetTimeFormat(TimeUnix)
+	if err := e.Encode(date); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Encode(date.Add(500 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if expected := append(epoch, epochFloat...); !bytes.Equal(buffer.Bytes(), expected) {
+		t.Fatalf("%#v != %#v", buffer.Bytes(), expected)
+	}
+
+	testDecoder(t, rfc3339, date)
+	testDecoder(t, epoch, date)
+	testDecoder(t, epochFloat, date.Add(500*time.Millisecond))
+	// without tags
+	testDecoder(t, rfc3339[1:], date)
+	testDecoder(t, epoch[1:], date)
+	testDecoder(t, []byte{0xc1, 0x20}, time.Unix(-1, 0).UTC())
+	// into interface{}
+	testDecoder(t, []byte{0x81, 0xc1, 0x1a, 0x51, 0x4b, 0x67, 0xb0}, []interface{}{date})
+
+	// tag 1 must be followed by a number
+	var x time.Time
+	testDecoderError(t, append([]byte{0xc1}, rfc3339[1:]...), &x)
+	testDecoderError(t, []byte{0xc0, 0x01}, &x)
+	testDecoderError(t, []byte{0xc0, 0x61, 0x61}, &x)
+}
+
+func TestTimeStruct(t *testing.T) {
+	type S struct {
+		Created time.Time  `cbor:"c"`
+		Updated *time.Time `cbor:"u,omitempty"`
+		private time.Time
+	}
+	var date = time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)
+	var buffer bytes.Buffer
+	var e = NewEncoder(&buffer)
+	e.SetTimeFormat(TimeUnix)
+	if err := e.Encode(S{Created: date, Updated: &date, private: date}); err != nil {
+		t.Fatal(err)
+	}
+	testDecoder(t, buffer.Bytes(), S{Created: date, Updated: &date})
+
+	// unexported fields of values that aren't addressable
+	var s = S{Created: date, private: date}
+	var encoded = []byte{
+		0xa2, 0x61, 0x63, 0xc1, 0x1a, 0x51, 0x4b, 0x67, 0xb0,
+		0x67, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0xc1, 0x1a, 0x51, 0x4b, 0x67, 0xb0,
+	}
+	buffer.Reset()
+	if err := e.Encode(map[string]S{"s": s}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := append([]byte{0xa1, 0x61, 0x73}, encoded...); !bytes.Equal(buffer.Bytes(), expected) {
+		t.Fatalf("%#v != %#v", buffer.Bytes(), expected)
+	}
+	buffer.Reset()
+	if err := e.Encode([]interface{}{s}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := append([]byte{0x81}, encoded...); !bytes.Equal(buffer.Bytes(), expected) {
+		t.Fatalf("%#v != %#v", buffer.Bytes(), expected)
+	}
+	testEncoder(t, map[int]struct{ n big.Int }{1: {}}, []byte{0xa1, 0x01, 0xa1, 0x61, 0x6e, 0x00})
+}
+
+func TestDate(t *testing.T) {
+	var date = Date{2013, time.March, 21}
+	var fullDate = []byte{
+		0xd9, 0x03, 0xec, 0x6a, 0x32, 0x30, 0x31, 0x33, 0x2d, 0x30, 0x33, 0x2d,
+		0x32, 0x31,
+	}
+	var days = []byte{0xd8, 0x64, 0x19, 0x3d, 0xa9}
+
+	testEncoder(t, date, fullDate)
+	var buffer bytes.Buffer
+	var e = NewEncoder(&buffer)
+	e.SetTimeFormat(TimeUnix)
+	if err := e.Encode(date); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buffer.Bytes(), days) {
+		t.Fatalf("%#v != %#v", buffer.Bytes(), days)
+	}
+
+	testDecoder(t, fullDate, date)
+	testDecoder(t, days, date)
+	testDecoder(t, fullDate[3:], date)
+	testDecoder(t, []byte{0xd8, 0x64, 0x20}, Date{1969, time.December, 31})
+	testDecoder(t, []byte{0x81, 0xd8, 0x64, 0x19, 0x3d, 0xa9}, []interface{}{date})
+	// into time.Time
+	testDecoder(t, days, date.In(time.UTC))
+
+	var d Date
+	testDecoderError(t, []byte{0xd8, 0x64, 0x61, 0x61}, &d)
+	testDecoderError(t, []byte{0xd9, 0x03, 0xec, 0x01}, &d)
+	testDecoderError(t, []byte{0xd9, 0x03, 0xec, 0x61, 0x61}, &d)
+	testDecoderError(t, []byte{0xd8, 0x64, 0x1b, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, &d)
+}
+
+func TestExtendedTime(t *testing.T) {
+	var date = time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)
+	var seconds = []byte{0x01, 0x1a, 0x51, 0x4b, 0x67, 0xb0}
+	var cases = []struct {
+		Value   time.Time
+		Encoded []byte
+	}{
+		{date, append([]byte{0xd9, 0x03, 0xe9, 0xa1}, seconds...)},
+		{
+			date.Add(500 * time.Millisecond),
+			append(append([]byte{0xd9, 0x03, 0xe9, 0xa2}, seconds...), 0x22, 0x19, 0x01, 0xf4),
+		},
+		{
+			date.Add(123456789),
+			append(append([]byte{0xd9, 0x03, 0xe9, 0xa2}, seconds...), 0x28, 0x1a, 0x07, 0x5b, 0xcd, 0x15),
+		},
+	}
+	var buffer bytes.Buffer
+	var e = NewEncoder(&buffer)
+	e.SetTimeFormat(TimeExtended)
+	for _, c := range cases {
+		buffer.Reset()
+		if err := e.Encode(c.Value); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buffer.Bytes(), c.Encoded) {
+			t.Fatalf("%#v != %#v", buffer.Bytes(), c.Encoded)
+		}
+		testDecoder(t, c.Encoded, c.Value)
+	}
+
+	// the time zone is kept as a hint
+	buffer.Reset()
+	if err := e.Encode(date.In(time.FixedZone("", 3600))); err != nil {
+		t.Fatal(err)
+	}
+	var expected = append(append([]byte{0xd9, 0x03, 0xe9, 0xa2}, seconds...), 0x29, 0x19, 0x0e, 0x10)
+	if !bytes.Equal(buffer.Bytes(), expected) {
+		t.Fatalf("%#v != %#v", buffer.Bytes(), expected)
+	}
+	var x time.Time
+	if err := NewDecoder(bytes.NewReader(expected)).Decode(&x); err != nil {
+		t.Fatal(err)
+	}
+	if _, offset := x.Zone(); !x.Equal(date) || offset != 3600 {
+		t.Fatalf("%s != %s", x, date.In(time.FixedZone("", 3600)))
+	}
+
+	// picoseconds
+	testDecoder(t,
+		append(append([]byte{0xd9, 0x03, 0xe9, 0xa2}, seconds...), 0x2b, 0x1a, 0x00, 0xbc, 0x5e, 0xa8),
+		date.Add(12345),
+	)
+	testDecoderError(t,
+		append(append([]byte{0xd9, 0x03, 0xe9, 0xa2}, seconds...), 0x2b, 0x19, 0x03, 0xe9),
+		&x,
+	)
+	// the base time is required, TAI isn't supported
+	testDecoderError(t, []byte{0xd9, 0x03, 0xe9, 0xa1, 0x28, 0x01}, &x)
+	testDecoderError(t, append(append([]byte{0xd9, 0x03, 0xe9, 0xa2}, seconds...), 0x20, 0x01), &x)
+	// fractions must be less than a second
+	testDecoderError(t,
+		append(append([]byte{0xd9, 0x03, 0xe9, 0xa2}, seconds...), 0x22, 0x19, 0x03, 0xe8),
+		&x,
+	)
+}
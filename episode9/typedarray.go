@@ -0,0 +1,317 @@
+package cbor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"unsafe"
+)
+
+// Typed arrays from RFC 8746 are byte strings holding packed numbers, the tag
+// tells the type of the numbers: 0b010_f_s_e_ll
+//
+//	f: 0 for integers, 1 for floating point numbers
+//	s: 0 for unsigned integers, 1 for signed integers
+//	e: 0 for big endian, 1 for little endian
+//	ll: the size of the numbers, 1 << ll bytes for integers, 2 << ll for floats
+const (
+	tagTypedArrayFirst  = 64
+	tagTypedArrayLast   = 87
+	typedArrayFloat     = 1 << 4
+	typedArraySigned    = 1 << 3
+	typedArrayLittleEnd = 1 << 2
+)
+
+// hostLittleEndian is true when the host stores numbers in little endian,
+// numbers can then be copied as is in and out of typed arrays
+var hostLittleEndian = func() bool {
+	var i uint16 = 1
+	return *(*byte)(unsafe.Pointer(&i)) == 1
+}()
+
+// typedArray describes the numbers in a typed array
+type typedArray struct {
+	kind         reflect.Kind // Uint64, Int64, or Float64
+	size         int
+	littleEndian bool
+}
+
+// parseTypedArrayTag returns the description of the numbers of the typed
+// array with the tag, ok is false if the tag isn't a supported typed array
+func parseTypedArrayTag(tag uint64) (a typedArray, ok bool) {
+	if tag < tagTypedArrayFirst || tag > tagTypedArrayLast {
+		return a, false
+	}
+	var t = tag - tagTypedArrayFirst
+	var ll = uint(t & 3)
+	a.littleEndian = t&typedArrayLittleEnd != 0
+	switch {
+	case t&typedArrayFloat != 0:
+		// 128 bits floats aren't supported
+		a.kind, a.size = reflect.Float64, 2<<ll
+		return a, a.size <= 8
+	case t&typedArraySigned != 0:
+		a.kind, a.size = reflect.Int64, 1<<ll
+	default:
+		// uint8 typed arrays with the little endian bit are clamped
+		a.kind, a.size = reflect.Uint64, 1<<ll
+	}
+	// the tag 76 is reserved
+	return a, !(a.kind == reflect.Int64 && a.size == 1 && a.littleEndian)
+}
+
+// elemType returns the Go type of the numbers in a typed array
+func (a typedArray) elemType() reflect.Type {
+	switch {
+	case a.kind == reflect.Float64 && a.size == 4:
+		return reflect.TypeOf(float32(0))
+	case a.kind == reflect.Float64:
+		// float16 are decoded as float64
+		return reflect.TypeOf(float64(0))
+	}
+	var types = map[reflect.Kind][]reflect.Type{
+		reflect.Uint64: {
+			reflect.TypeOf(uint8(0)), reflect.TypeOf(uint16(0)),
+			reflect.TypeOf(uint32(0)), reflect.TypeOf(uint64(0)),
+		},
+		reflect.Int64: {
+			reflect.TypeOf(int8(0)), reflect.TypeOf(int16(0)),
+			reflect.TypeOf(int32(0)), reflect.TypeOf(int64(0)),
+		},
+	}
+	switch a.size {
+	case 1:
+		return types[a.kind][0]
+	case 2:
+		return types[a.kind][1]
+	case 4:
+		return types[a.kind][2]
+	default:
+		return types[a.kind][3]
+	}
+}
+
+func init() {
+	// typed arrays are decoded as slices in an empty interface
+	for tag := uint64(tagTypedArrayFirst); tag <= tagTypedArrayLast; tag++ {
+		if a, ok := parseTypedArrayTag(tag); ok {
+			tagTypes[tag] = reflect.SliceOf(a.elemType())
+		}
+	}
+}
+
+// typedArrayTag returns the tag of the typed array e writes for slices of t, ok
+// is false if typed arrays are disabled or if t isn't written as a number:
+// durations have their own format unless they're written in nanoseconds.
+func (e *Encoder) typedArrayTag(t reflect.Type) (tag uint64, ok bool) {
+	if !e.typedArrays || (t == durationType && e.durationFormat != DurationNanoseconds) {
+		return 0, false
+	}
+	return typedArrayTag(t)
+}
+
+// typedArrayTag returns the tag of the little endian typed array for numbers
+// of type t, ok is false if t isn't a number
+func typedArrayTag(t reflect.Type) (tag uint64, ok bool) {
+	if t == simpleValueType {
+		return 0, false
+	}
+	var ll uint64
+	switch t.Size() {
+	case 1:
+		ll = 0
+	case 2:
+		ll = 1
+	case 4:
+		ll = 2
+	default:
+		ll = 3
+	}
+	switch t.Kind() {
+	case reflect.Uint8:
+		return tagTypedArrayFirst, true
+	case reflect.Int8:
+		return tagTypedArrayFirst | typedArraySigned, true
+	case reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		return tagTypedArrayFirst | typedArrayLittleEnd | ll, true
+	case reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		return tagTypedArrayFirst | typedArraySigned | typedArrayLittleEnd | ll, true
+	case reflect.Float32, reflect.Float64:
+		return tagTypedArrayFirst | typedArrayFloat | typedArrayLittleEnd | (ll - 1), true
+	}
+	return 0, false
+}
+
+// writeTypedArray writes the slice of numbers x as a little endian typed array
+func (e *Encoder) writeTypedArray(tag uint64, x reflect.Value) error {
+	if err := e.writeTag(tag); err != nil {
+		return err
+	}
+	var size = int(x.Type().Elem().Size())
+	if hostLittleEndian && x.Len() > 0 {
+		// the numbers are already in the right format in memory
+		return e.writeByteString(unsafe.Slice((*byte)(x.UnsafePointer()), x.Len()*size))
+	}
+	var b = make([]byte, x.Len()*size)
+	for i := 0; i < x.Len(); i++ {
+		var bits uint64
+		var item = x.Index(i)
+		switch item.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			bits = uint64(item.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			bits = item.Uint()
+		case reflect.Float32:
+			bits = uint64(math.Float32bits(float32(item.Float())))
+		default:
+			bits = math.Float64bits(item.Float())
+		}
+		putUint(binary.LittleEndian, b[i*size:(i+1)*size], bits)
+	}
+	return e.writeByteString(b)
+}
+
+// putUint writes the len(b) least significant bytes of v in b
+func putUint(order binary.ByteOrder, b []byte, v uint64) {
+	switch len(b) {
+	case 1:
+		b[0] = byte(v)
+	case 2:
+		order.PutUint16(b, uint16(v))
+	case 4:
+		order.PutUint32(b, uint32(v))
+	default:
+		order.PutUint64(b, v)
+	}
+}
+
+// getUint reads an unsigned integer of len(b) bytes from b
+func getUint(order binary.ByteOrder, b []byte) uint64 {
+	switch len(b) {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(order.Uint16(b))
+	case 4:
+		return uint64(order.Uint32(b))
+	default:
+		return order.Uint64(b)
+	}
+}
+
+// isNumberKind reports whether values of kind k are integers or floats
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// decodeTypedArray decodes a typed array into v, a slice or an array of
+// numbers or empty interfaces. When the numbers in the typed array are in the
+// host's format the slice uses the decoded bytes directly.
+func (d *Decoder) decodeTypedArray(a typedArray, major, minor byte, v reflect.Value) error {
+	if major != majorByteString {
+		return d.typeError(major, minor, v)
+	}
+	b, err := d.readString(major, minor)
+	if err != nil {
+		return err
+	}
+	if len(b)%a.size != 0 {
+		return ErrMalformed
+	}
+	var n = len(b) / a.size
+	var elemType = v.Type().Elem()
+
+	if v.Kind() == reflect.Slice {
+		var native = elemType.Size() == uintptr(a.size) && (a.size == 1 || a.littleEndian == hostLittleEndian)
+		switch elemType.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			native = native && a.kind == reflect.Uint64
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			native = native && a.kind == reflect.Int64
+		default:
+			native = native && a.kind == reflect.Float64 && a.size != 2
+		}
+		if native && n > 0 && uintptr(unsafe.Pointer(&b[0]))%uintptr(a.size) == 0 {
+			v.Set(reflect.SliceAt(elemType, unsafe.Pointer(&b[0]), n).Convert(v.Type()))
+			return nil
+		}
+		v.Set(reflect.MakeSlice(v.Type(), n, n))
+	}
+
+	var order binary.ByteOrder = binary.BigEndian
+	if a.littleEndian {
+		order = binary.LittleEndian
+	}
+	for i := 0; i < n && i < v.Len(); i++ {
+		var bits = getUint(order, b[i*a.size:(i+1)*a.size])
+		if err := setNumber(a, bits, v.Index(i)); err != nil {
+			return err
+		}
+	}
+	// zero the rest of the array
+	for i := n; i < v.Len(); i++ {
+		v.Index(i).Set(reflect.Zero(elemType))
+	}
+	return nil
+}
+
+// setNumber stores the number from a typed array with the raw value bits in v
+func setNumber(a typedArray, bits uint64, v reflect.Value) error {
+	if v.Kind() == reflect.Interface {
+		var x = reflect.New(a.elemType()).Elem()
+		if err := setNumber(a, bits, x); err != nil {
+			return err
+		}
+		v.Set(x)
+		return nil
+	}
+	var (
+		i int64
+		u uint64
+		f float64
+	)
+	switch a.kind {
+	case reflect.Uint64:
+		u, i, f = bits, int64(bits), float64(bits)
+	case reflect.Int64:
+		// sign extend the integer
+		var shift = uint(64 - 8*a.size)
+		i = int64(bits<<shift) >> shift
+		u, f = uint64(i), float64(i)
+	default:
+		switch a.size {
+		case 2:
+			f = float16ToFloat64(uint16(bits))
+		case 4:
+			f = float64(math.Float32frombits(uint32(bits)))
+		default:
+			f = math.Float64frombits(bits)
+		}
+	}
+	var overflow bool
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		overflow = a.kind == reflect.Float64 || (a.kind == reflect.Uint64 && i < 0) || v.OverflowInt(i)
+		if !overflow {
+			v.SetInt(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		overflow = a.kind == reflect.Float64 || (a.kind == reflect.Int64 && i < 0) || v.OverflowUint(u)
+		if !overflow {
+			v.SetUint(u)
+		}
+	default:
+		v.SetFloat(f)
+	}
+	if overflow {
+		return fmt.Errorf("cbor: typed array item overflows Go value of type %s", v.Type())
+	}
+	return nil
+}
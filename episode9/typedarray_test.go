@@ -0,0 +1,94 @@
+package cbor
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// testTypedArrayEncoder encodes v with typed arrays enabled and verifies the
+// output matches expected
+func testTypedArrayEncoder(t *testing.T, v interface{}, expected []byte) {
+	var buffer bytes.Buffer
+	var e = NewEncoder(&buffer)
+	e.SetTypedArrays(true)
+	if err := e.Encode(v); err != nil {
+		t.Fatalf("err: %#v != nil with %#v", err, v)
+	}
+	if !bytes.Equal(buffer.Bytes(), expected) {
+		t.Fatalf("(%#v) %#v != %#v", v, buffer.Bytes(), expected)
+	}
+}
+
+func TestTypedArray(t *testing.T) {
+	var cases = []struct {
+		Value    interface{}
+		Expected []byte
+	}{
+		{Value: []int8{-1, 2}, Expected: []byte{0xd8, 0x48, 0x42, 0xff, 0x02}},
+		{Value: []uint16{1, 0x0203}, Expected: []byte{0xd8, 0x45, 0x44, 0x01, 0x00, 0x03, 0x02}},
+		{Value: []int32{-2}, Expected: []byte{0xd8, 0x4e, 0x44, 0xfe, 0xff, 0xff, 0xff}},
+		{Value: [1]uint64{1}, Expected: []byte{0xd8, 0x47, 0x48, 1, 0, 0, 0, 0, 0, 0, 0}},
+		{Value: []float32{1.5}, Expected: []byte{0xd8, 0x55, 0x44, 0x00, 0x00, 0xc0, 0x3f}},
+		{
+			Value:    []float64{-2},
+			Expected: []byte{0xd8, 0x56, 0x48, 0, 0, 0, 0, 0, 0, 0x00, 0xc0},
+		},
+		{Value: []float64{}, Expected: []byte{0xd8, 0x56, 0x40}},
+	}
+	for _, c := range cases {
+		testTypedArrayEncoder(t, c.Value, c.Expected)
+		testDecoder(t, c.Expected, c.Value)
+	}
+	// typed arrays are disabled by default
+	testEncoder(t, []uint16{1, 2}, []byte{0x82, 0x01, 0x02})
+	// []byte is still a byte string
+	testTypedArrayEncoder(t, []byte{1, 2}, []byte{0x42, 0x01, 0x02})
+}
+
+func TestTypedArrayDecode(t *testing.T) {
+	// big endian arrays
+	testDecoder(t, []byte{0xd8, 0x41, 0x44, 0x01, 0x02, 0x03, 0x04}, []uint16{0x0102, 0x0304})
+	testDecoder(t, []byte{0xd8, 0x49, 0x42, 0xff, 0xfe}, []int16{-2})
+	testDecoder(t, []byte{0xd8, 0x51, 0x44, 0x3f, 0xc0, 0x00, 0x00}, []float32{1.5})
+	// float16
+	testDecoder(t, []byte{0xd8, 0x54, 0x42, 0x00, 0x3c}, []float64{1})
+	// conversions
+	testDecoder(t, []byte{0xd8, 0x45, 0x44, 0x01, 0x00, 0x03, 0x02}, []int{1, 0x0203})
+	testDecoder(t, []byte{0xd8, 0x45, 0x44, 0x01, 0x00, 0x03, 0x02}, [3]float64{1, 0x0203, 0})
+	// into interface{}
+	testDecoder(t,
+		[]byte{0x82, 0xd8, 0x45, 0x42, 0x01, 0x00, 0xd8, 0x54, 0x42, 0x00, 0x7c},
+		[]interface{}{[]uint16{1}, []float64{math.Inf(1)}},
+	)
+	// regular arrays still work
+	testDecoder(t, []byte{0x82, 0x01, 0x02}, []uint16{1, 2})
+
+	var u8 []uint8
+	testDecoderError(t, []byte{0xd8, 0x48, 0x41, 0xff}, &u8)
+	var u16 []uint16
+	testDecoderError(t, []byte{0xd8, 0x45, 0x43, 0x01, 0x00, 0x03}, &u16)
+	var f []float64
+	testDecoderError(t, []byte{0xd8, 0x53, 0x40}, &f)
+}
+
+func BenchmarkTypedArray(b *testing.B) {
+	var samples = make([]float32, 1<<20)
+	for i := range samples {
+		samples[i] = float32(i)
+	}
+	var buffer bytes.Buffer
+	var e = NewEncoder(&buffer)
+	e.SetTypedArrays(true)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buffer.Reset()
+		if err := e.Encode(samples); err != nil {
+			b.Fatal(err)
+		}
+		var decoded []float32
+		if err := NewDecoder(&buffer).Decode(&decoded); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package cbor
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"unicode/utf8"
+)
+
+// InvalidUTF8 selects how the encoder writes strings that aren't valid UTF-8
+type InvalidUTF8 int
+
+const (
+	// InvalidUTF8Allow writes invalid strings as text strings, strict
+	// decoders will reject them
+	InvalidUTF8Allow InvalidUTF8 = iota
+	// InvalidUTF8Reject returns an error for invalid strings
+	InvalidUTF8Reject
+	// InvalidUTF8AsByteString writes invalid strings as byte strings
+	InvalidUTF8AsByteString
+)
+
+// ErrInvalidUTF8 is returned by Decode for text strings that aren't valid
+// UTF-8 when strict UTF-8 is enabled
+var ErrInvalidUTF8 = errors.New("cbor: invalid UTF-8 in text string")
+
+// SetInvalidUTF8 selects how strings that aren't valid UTF-8 are encoded, the
+// default is InvalidUTF8Allow
+func (e *Encoder) SetInvalidUTF8(mode InvalidUTF8) {
+	e.invalidUTF8 = mode
+}
+
+// writeInvalidUTF8 writes s if it isn't valid UTF-8 and invalid strings
+// aren't allowed. done is false if s must be written as a text string.
+func (e *Encoder) writeInvalidUTF8(s string) (done bool, err error) {
+	if e.invalidUTF8 == InvalidUTF8Allow || utf8.ValidString(s) {
+		return false, nil
+	}
+	if e.invalidUTF8 == InvalidUTF8AsByteString {
+		return true, e.writeByteString([]byte(s))
+	}
+	return true, &UnsupportedValueError{
+		reflect.ValueOf(s),
+		fmt.Sprintf("invalid UTF-8 in string at byte %d", invalidUTF8Offset(s)),
+	}
+}
+
+// invalidUTF8Offset returns the offset of the first invalid byte of s
+func invalidUTF8Offset(s string) int {
+	for i, r := range s {
+		if r == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(s[i:]); size == 1 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+// SetStrictUTF8 enables or disables the validation of text strings, Decode
+// returns ErrInvalidUTF8 for text strings that aren't valid UTF-8 when it's
+// enabled. The chunks of indefinite length strings must be valid on their own.
+func (d *Decoder) SetStrictUTF8(enabled bool) {
+	d.strictUTF8 = enabled
+}
+
+// checkUTF8 returns ErrInvalidUTF8 if s is an invalid text string and text
+// strings are validated
+func (d *Decoder) checkUTF8(major byte, s []byte) error {
+	if d.strictUTF8 && major == majorUnicodeString && !utf8.Valid(s) {
+		return ErrInvalidUTF8
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package cbor
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestInvalidUTF8(t *testing.T) {
+	var invalid = "a\xffb"
+	testEncoder(t, invalid, []byte{0x63, 0x61, 0xff, 0x62})
+
+	var e = NewEncoder(io.Discard)
+	e.SetInvalidUTF8(InvalidUTF8Reject)
+	testEncoderUnsupported(t, e, invalid)
+	testEncoderUnsupported(t, e, map[string]int{invalid: 1})
+	if err := e.Encode("héllo"); err != nil {
+		t.Fatal(err)
+	}
+	// the error reports where the string is invalid, not the whole string
+	var err = e.Encode("héllo\xff" + strings.Repeat("a", 1000))
+	if expected := "cbor: unsupported value: invalid UTF-8 in string at byte 6"; err == nil || err.Error() != expected {
+		t.Fatalf("%v != %v", err, expected)
+	}
+
+	var buffer bytes.Buffer
+	e = NewEncoder(&buffer)
+	e.SetInvalidUTF8(InvalidUTF8AsByteString)
+	if err := e.Encode([]string{invalid, "ab"}); err != nil {
+		t.Fatal(err)
+	}
+	var expected = []byte{0x82, 0x43, 0x61, 0xff, 0x62, 0x62, 0x61, 0x62}
+	if !bytes.Equal(buffer.Bytes(), expected) {
+		t.Fatalf("%#v != %#v", buffer.Bytes(), expected)
+	}
+}
+
+func TestDecodeStrictUTF8(t *testing.T) {
+	var cases = [][]byte{
+		{0x63, 0x61, 0xff, 0x62},
+		// chunks must be valid on their own
+		{0x7f, 0x61, 0xc3, 0x61, 0xa9, 0xff},
+		// in skipped values
+		{0xa1, 0x61, 0x62, 0x61, 0xff},
+	}
+	for _, input := range cases {
+		// strings aren't validated by default
+		var s struct {
+			A string `cbor:"a"`
+		}
+		if err := NewDecoder(bytes.NewReader(input)).Decode(new(interface{})); err != nil {
+			t.Fatal(err)
+		}
+		var d = NewDecoder(bytes.NewReader(input))
+		d.SetStrictUTF8(true)
+		if err := d.Decode(&s); err != ErrInvalidUTF8 {
+			t.Fatalf("%#v != ErrInvalidUTF8 with %#v", err, input)
+		}
+	}
+
+	var d = NewDecoder(bytes.NewReader([]byte{0x82, 0x62, 0xc3, 0xa9, 0x41, 0xff}))
+	d.SetStrictUTF8(true)
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+}